@@ -0,0 +1,223 @@
+package ghhook
+
+import "fmt"
+
+// Action is the 'action' of a webhook payload, ie 'opened' for pull_request
+// events. Constants are named after the event and the action, so typos are
+// compile errors.
+type Action string
+
+const (
+	CommitCommentCreated Action = "created"
+
+	InstallationCreated                Action = "created"
+	InstallationDeleted                Action = "deleted"
+	InstallationNewPermissionsAccepted Action = "new_permissions_accepted"
+	InstallationSuspend                Action = "suspend"
+	InstallationUnsuspend              Action = "unsuspend"
+
+	IntegrationInstallationCreated Action = "created"
+	IntegrationInstallationDeleted Action = "deleted"
+
+	IssueCommentCreated Action = "created"
+	IssueCommentEdited  Action = "edited"
+	IssueCommentDeleted Action = "deleted"
+
+	IssuesOpened       Action = "opened"
+	IssuesEdited       Action = "edited"
+	IssuesDeleted      Action = "deleted"
+	IssuesTransferred  Action = "transferred"
+	IssuesPinned       Action = "pinned"
+	IssuesUnpinned     Action = "unpinned"
+	IssuesClosed       Action = "closed"
+	IssuesReopened     Action = "reopened"
+	IssuesAssigned     Action = "assigned"
+	IssuesUnassigned   Action = "unassigned"
+	IssuesLabeled      Action = "labeled"
+	IssuesUnlabeled    Action = "unlabeled"
+	IssuesLocked       Action = "locked"
+	IssuesUnlocked     Action = "unlocked"
+	IssuesMilestoned   Action = "milestoned"
+	IssuesDemilestoned Action = "demilestoned"
+
+	LabelCreated Action = "created"
+	LabelEdited  Action = "edited"
+	LabelDeleted Action = "deleted"
+
+	MemberAdded   Action = "added"
+	MemberRemoved Action = "removed"
+	MemberEdited  Action = "edited"
+
+	MembershipAdded   Action = "added"
+	MembershipRemoved Action = "removed"
+
+	MilestoneCreated Action = "created"
+	MilestoneClosed  Action = "closed"
+	MilestoneOpened  Action = "opened"
+	MilestoneEdited  Action = "edited"
+	MilestoneDeleted Action = "deleted"
+
+	OrganizationDeleted       Action = "deleted"
+	OrganizationRenamed       Action = "renamed"
+	OrganizationMemberAdded   Action = "member_added"
+	OrganizationMemberRemoved Action = "member_removed"
+	OrganizationMemberInvited Action = "member_invited"
+
+	OrgBlockBlocked   Action = "blocked"
+	OrgBlockUnblocked Action = "unblocked"
+
+	ProjectCardCreated   Action = "created"
+	ProjectCardEdited    Action = "edited"
+	ProjectCardConverted Action = "converted"
+	ProjectCardMoved     Action = "moved"
+	ProjectCardDeleted   Action = "deleted"
+
+	ProjectColumnCreated Action = "created"
+	ProjectColumnEdited  Action = "edited"
+	ProjectColumnMoved   Action = "moved"
+	ProjectColumnDeleted Action = "deleted"
+
+	ProjectCreated  Action = "created"
+	ProjectEdited   Action = "edited"
+	ProjectClosed   Action = "closed"
+	ProjectReopened Action = "reopened"
+	ProjectDeleted  Action = "deleted"
+
+	PullRequestAssigned             Action = "assigned"
+	PullRequestUnassigned           Action = "unassigned"
+	PullRequestReviewRequested      Action = "review_requested"
+	PullRequestReviewRequestRemoved Action = "review_request_removed"
+	PullRequestLabeled              Action = "labeled"
+	PullRequestUnlabeled            Action = "unlabeled"
+	PullRequestOpened               Action = "opened"
+	PullRequestEdited               Action = "edited"
+	PullRequestClosed               Action = "closed"
+	PullRequestReopened             Action = "reopened"
+	PullRequestSynchronize          Action = "synchronize"
+	PullRequestReadyForReview       Action = "ready_for_review"
+	PullRequestConvertedToDraft     Action = "converted_to_draft"
+	PullRequestLocked               Action = "locked"
+	PullRequestUnlocked             Action = "unlocked"
+
+	PullRequestReviewSubmitted Action = "submitted"
+	PullRequestReviewEdited    Action = "edited"
+	PullRequestReviewDismissed Action = "dismissed"
+
+	PullRequestReviewCommentCreated Action = "created"
+	PullRequestReviewCommentEdited  Action = "edited"
+	PullRequestReviewCommentDeleted Action = "deleted"
+
+	ReleasePublished   Action = "published"
+	ReleaseUnpublished Action = "unpublished"
+	ReleaseCreated     Action = "created"
+	ReleaseEdited      Action = "edited"
+	ReleaseDeleted     Action = "deleted"
+	ReleasePrereleased Action = "prereleased"
+	ReleaseReleased    Action = "released"
+
+	RepositoryCreated     Action = "created"
+	RepositoryDeleted     Action = "deleted"
+	RepositoryArchived    Action = "archived"
+	RepositoryUnarchived  Action = "unarchived"
+	RepositoryEdited      Action = "edited"
+	RepositoryRenamed     Action = "renamed"
+	RepositoryTransferred Action = "transferred"
+	RepositoryPublicized  Action = "publicized"
+	RepositoryPrivatized  Action = "privatized"
+
+	TeamCreated               Action = "created"
+	TeamDeleted               Action = "deleted"
+	TeamEdited                Action = "edited"
+	TeamAddedToRepository     Action = "added_to_repository"
+	TeamRemovedFromRepository Action = "removed_from_repository"
+
+	WatchStarted Action = "started"
+)
+
+// EventActions lists the actions of the events that have them. Events that
+// are not listed, ie push, have no action.
+var EventActions = map[Event][]Action{
+	CommitCommentEvent:            {CommitCommentCreated},
+	InstallationEvent:             {InstallationCreated, InstallationDeleted, InstallationNewPermissionsAccepted, InstallationSuspend, InstallationUnsuspend},
+	IntegrationInstallationEvent:  {IntegrationInstallationCreated, IntegrationInstallationDeleted},
+	IssueCommentEvent:             {IssueCommentCreated, IssueCommentEdited, IssueCommentDeleted},
+	IssuesEvent:                   {IssuesOpened, IssuesEdited, IssuesDeleted, IssuesTransferred, IssuesPinned, IssuesUnpinned, IssuesClosed, IssuesReopened, IssuesAssigned, IssuesUnassigned, IssuesLabeled, IssuesUnlabeled, IssuesLocked, IssuesUnlocked, IssuesMilestoned, IssuesDemilestoned},
+	LabelEvent:                    {LabelCreated, LabelEdited, LabelDeleted},
+	MemberEvent:                   {MemberAdded, MemberRemoved, MemberEdited},
+	MembershipEvent:               {MembershipAdded, MembershipRemoved},
+	MilestoneEvent:                {MilestoneCreated, MilestoneClosed, MilestoneOpened, MilestoneEdited, MilestoneDeleted},
+	OrganizationEvent:             {OrganizationDeleted, OrganizationRenamed, OrganizationMemberAdded, OrganizationMemberRemoved, OrganizationMemberInvited},
+	OrgBlockEvent:                 {OrgBlockBlocked, OrgBlockUnblocked},
+	ProjectCardEvent:              {ProjectCardCreated, ProjectCardEdited, ProjectCardConverted, ProjectCardMoved, ProjectCardDeleted},
+	ProjectColumnEvent:            {ProjectColumnCreated, ProjectColumnEdited, ProjectColumnMoved, ProjectColumnDeleted},
+	ProjectEvent:                  {ProjectCreated, ProjectEdited, ProjectClosed, ProjectReopened, ProjectDeleted},
+	PullRequestEvent:              {PullRequestAssigned, PullRequestUnassigned, PullRequestReviewRequested, PullRequestReviewRequestRemoved, PullRequestLabeled, PullRequestUnlabeled, PullRequestOpened, PullRequestEdited, PullRequestClosed, PullRequestReopened, PullRequestSynchronize, PullRequestReadyForReview, PullRequestConvertedToDraft, PullRequestLocked, PullRequestUnlocked},
+	PullRequestReviewEvent:        {PullRequestReviewSubmitted, PullRequestReviewEdited, PullRequestReviewDismissed},
+	PullRequestReviewCommentEvent: {PullRequestReviewCommentCreated, PullRequestReviewCommentEdited, PullRequestReviewCommentDeleted},
+	ReleaseEvent:                  {ReleasePublished, ReleaseUnpublished, ReleaseCreated, ReleaseEdited, ReleaseDeleted, ReleasePrereleased, ReleaseReleased},
+	RepositoryEvent:               {RepositoryCreated, RepositoryDeleted, RepositoryArchived, RepositoryUnarchived, RepositoryEdited, RepositoryRenamed, RepositoryTransferred, RepositoryPublicized, RepositoryPrivatized},
+	TeamEvent:                     {TeamCreated, TeamDeleted, TeamEdited, TeamAddedToRepository, TeamRemovedFromRepository},
+	WatchEvent:                    {WatchStarted},
+}
+
+// Route registers InputFn for an event, restricted to some of its actions. It's
+// returned by On.
+type Route struct {
+	event   Event
+	actions []Action
+}
+
+// On returns a Route for the given event and actions. It panics if any of the
+// actions doesn't exist for the event, so mistakes are reported when handlers
+// are registered instead of silently dropping events.
+//
+// Example:
+//
+//	ghhook.On(ghhook.PullRequestEvent, ghhook.PullRequestOpened, ghhook.PullRequestReopened).Handle(
+//	  func(e interface{}) (*ghhook.Response, error) { return nil, nil },
+//	)
+func On(event Event, actions ...Action) *Route {
+	for _, action := range actions {
+		if !ValidAction(event, action) {
+			panic(fmt.Sprintf("ERROR: event '%s' has no action '%s'", event, action))
+		}
+	}
+
+	return &Route{event: event, actions: actions}
+}
+
+// Handle registers the InputFn with EventHandlerWithOptions, so it only runs
+// for the actions of the route and the given options.
+func (r *Route) Handle(fn InputFn, opts ...HandlerOption) {
+	if len(r.actions) > 0 {
+		opts = append([]HandlerOption{actionOption(r.actions)}, opts...)
+	}
+
+	EventHandlerWithOptions(r.event, fn, opts...)
+}
+
+// ValidAction returns true if the action exists for the event.
+func ValidAction(event Event, action Action) bool {
+	for _, a := range EventActions[event] {
+		if a == action {
+			return true
+		}
+	}
+
+	return false
+}
+
+func actionOption(actions []Action) HandlerOption {
+	return func(c *handlerConfig) {
+		c.add(mapCondition(func(m map[string]interface{}) string {
+			action, _ := m["action"].(string)
+			for _, a := range actions {
+				if Action(action) == a {
+					return ""
+				}
+			}
+
+			return fmt.Sprintf("action '%s' is not in '%v'", action, actions)
+		}))
+	}
+}
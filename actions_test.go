@@ -0,0 +1,53 @@
+package ghhook
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOn(t *testing.T) {
+	Convey("On", t, func() {
+		Reset(func() { ResetHandlers() })
+
+		var fn InputFn = func(e interface{}) (*Response, error) { return localSuccessResp("ok") }
+
+		Convey("It runs fn for the given actions", func() {
+			On(PullRequestEvent, PullRequestOpened, PullRequestReopened).Handle(fn)
+
+			resp, err := DefaultHandler(PullRequestProxyRequest)
+			So(err, ShouldBeNil)
+			So(resp.Body, ShouldEqual, "ok")
+		})
+
+		Convey("It drops other actions", func() {
+			On(PullRequestEvent, PullRequestClosed, PullRequestSynchronize).Handle(fn)
+
+			resp, err := DefaultHandler(PullRequestProxyRequest)
+			So(err, ShouldBeNil)
+			So(resp.Body, ShouldEqual, "Dropping unmatched event for option: action 'opened' is not in '[closed synchronize]'")
+		})
+
+		Convey("It runs fn for every action without actions", func() {
+			On(CreateEvent).Handle(fn)
+
+			resp, err := DefaultHandler(CreateEventProxyRequest)
+			So(err, ShouldBeNil)
+			So(resp.Body, ShouldEqual, "ok")
+		})
+
+		Convey("It applies the options", func() {
+			On(PullRequestEvent, PullRequestOpened).Handle(fn, InRepos("WalkerAndCoBrandsInc/*"))
+
+			resp, err := DefaultHandler(PullRequestProxyRequest)
+			So(err, ShouldBeNil)
+			So(resp.Body, ShouldStartWith, "Dropping unmatched event for option: repository")
+		})
+
+		Convey("It panics on actions that don't exist for the event", func() {
+			So(func() { On(IssuesEvent, PullRequestSynchronize) }, ShouldPanicWith, "ERROR: event 'issues' has no action 'synchronize'")
+			So(func() { On(PushEvent, PullRequestOpened) }, ShouldPanic)
+			So(func() { On(PullRequestEvent, Action("reopend")) }, ShouldPanic)
+		})
+	})
+}
@@ -0,0 +1,258 @@
+package ghhook
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ArchivedDelivery is a delivery received by DefaultHandler with its original
+// headers and raw body. It's encoded as a Delivery with additional fields, so
+// archived files can also be republished over SNS or EventBridge.
+type ArchivedDelivery struct {
+	ID         string    `json:"id"`
+	Event      Event     `json:"event"`
+	ReceivedAt time.Time `json:"received_at"`
+
+	Delivery
+}
+
+// Archive persists raw deliveries, keyed by delivery id and date, so they can
+// be replayed later.
+type Archive interface {
+	// Store persists the delivery.
+	Store(d *ArchivedDelivery) error
+
+	// Load returns the delivery with the given id received on the day of date.
+	// It returns ErrDeliveryNotArchived if there's no such delivery.
+	Load(id string, date time.Time) (*ArchivedDelivery, error)
+
+	// Walk calls fn for the deliveries received between from and to, to
+	// excluded, in the order they were received. Zero times are unbounded.
+	// A delivery stored more than once, ie redelivered by Github on a later
+	// day, is only passed the first time it was received. Walk stops at the
+	// first error of fn and returns it.
+	Walk(from, to time.Time, fn func(d *ArchivedDelivery) error) error
+}
+
+var (
+	// DeliveryArchive is used by DefaultHandler to archive deliveries that
+	// pass SourceIPAllowlist, signature verification, ReplayProtection and
+	// Deliveries, including events with no InputFn, so new handlers can be
+	// backfilled. Deliveries without a
+	// 'X-GitHub-Delivery' header are not archived. It's disabled when nil.
+	//
+	// Deliveries that can't be archived fail, so Github can redeliver them.
+	DeliveryArchive Archive
+
+	// ErrDeliveryNotArchived is returned by Archive.Load for unknown
+	// deliveries.
+	ErrDeliveryNotArchived = errors.New("ERROR: delivery is not archived")
+
+	// ErrInvalidEventName is returned by DirArchive for event names that can't
+	// be safely used as directory names.
+	ErrInvalidEventName = errors.New("ERROR: invalid event name")
+)
+
+// archiveDelivery stores the request in DeliveryArchive, unless it's a
+// replay.
+func archiveDelivery(rec *DeliveryRecord, eventName string) error {
+	if DeliveryArchive == nil || rec.DeliveryID == "" || rec.Replay {
+		return nil
+	}
+
+	return DeliveryArchive.Store(&ArchivedDelivery{
+		ID:         rec.DeliveryID,
+		Event:      Event(eventName),
+		ReceivedAt: rec.Start,
+		Delivery: Delivery{
+			Headers: rec.headers,
+			Body:    string(rec.payload.body),
+		},
+	})
+}
+
+// ReplayDelivery dispatches the archived delivery through the same code path as
+// DefaultHandler, and returns its response and record. Replayed deliveries
+// skip SourceIPAllowlist, ReplayProtection, Deliveries and DeliveryArchive,
+// but their signature is verified when WebhookSecret is set. Their record has
+// Replay set and is passed to DeliveryLogger, but not to Metrics or AuditLog.
+func ReplayDelivery(d *ArchivedDelivery) (*events.APIGatewayProxyResponse, *DeliveryRecord, error) {
+	r, err := d.ProxyRequest()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, ok := headerValue(r.Headers, "X-GitHub-Event"); !ok && d.Event != "" {
+		headers := map[string]string{"X-GitHub-Event": string(d.Event)}
+		for k, v := range r.Headers {
+			headers[k] = v
+		}
+		r.Headers = headers
+	}
+
+	rec := newDeliveryRecord(r)
+	rec.Replay = true
+
+	resp, err := handleDelivery(r, rec)
+	rec.finish(resp)
+
+	return resp, rec, err
+}
+
+// DirArchive is an Archive that keeps one JSON file per delivery in a local
+// directory, laid out as 'YYYY/MM/DD/<event>/<delivery>.json' in UTC.
+type DirArchive struct {
+	dir string
+}
+
+// NewDirArchive returns a DirArchive that keeps its files in dir. The
+// directory is created if it doesn't exist.
+func NewDirArchive(dir string) (*DirArchive, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &DirArchive{dir: dir}, nil
+}
+
+func (a *DirArchive) Store(d *ArchivedDelivery) error {
+	path, err := a.path(d.ID, d.Event, d.ReceivedAt)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so readers never see a partial file.
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func (a *DirArchive) Load(id string, date time.Time) (*ArchivedDelivery, error) {
+	if !deliveryIDRegexp.MatchString(id) || id == "." || id == ".." {
+		return nil, ErrInvalidDeliveryID
+	}
+
+	paths, err := filepath.Glob(filepath.Join(a.dayDir(date), "*", id+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		return nil, ErrDeliveryNotArchived
+	}
+
+	return ReadArchivedDelivery(paths[0])
+}
+
+func (a *DirArchive) Walk(from, to time.Time, fn func(d *ArchivedDelivery) error) error {
+	days, err := filepath.Glob(filepath.Join(a.dir, "[0-9][0-9][0-9][0-9]", "[0-9][0-9]", "[0-9][0-9]"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(days)
+
+	seen := map[string]bool{}
+	for _, day := range days {
+		if !from.IsZero() && day < a.dayDir(from) {
+			continue
+		}
+		if !to.IsZero() && day > a.dayDir(to) {
+			break
+		}
+
+		paths, err := filepath.Glob(filepath.Join(day, "*", "*.json"))
+		if err != nil {
+			return err
+		}
+
+		deliveries := make([]*ArchivedDelivery, 0, len(paths))
+		for _, path := range paths {
+			d, err := ReadArchivedDelivery(path)
+			if err != nil {
+				return err
+			}
+
+			if (!from.IsZero() && d.ReceivedAt.Before(from)) || (!to.IsZero() && !d.ReceivedAt.Before(to)) {
+				continue
+			}
+
+			deliveries = append(deliveries, d)
+		}
+
+		sort.SliceStable(deliveries, func(i, j int) bool {
+			return deliveries[i].ReceivedAt.Before(deliveries[j].ReceivedAt)
+		})
+
+		for _, d := range deliveries {
+			if seen[d.ID] {
+				continue
+			}
+			seen[d.ID] = true
+
+			if err := fn(d); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (a *DirArchive) path(id string, event Event, date time.Time) (string, error) {
+	if !deliveryIDRegexp.MatchString(id) || id == "." || id == ".." {
+		return "", ErrInvalidDeliveryID
+	}
+
+	if !deliveryIDRegexp.MatchString(string(event)) || event == "." || event == ".." {
+		return "", ErrInvalidEventName
+	}
+
+	return filepath.Join(a.dayDir(date), string(event), id+".json"), nil
+}
+
+func (a *DirArchive) dayDir(date time.Time) string {
+	return filepath.Join(a.dir, date.UTC().Format("2006/01/02"))
+}
+
+// ReadArchivedDelivery reads an archived delivery from a JSON file. Files of a
+// plain Delivery get their id and event from its headers.
+func ReadArchivedDelivery(path string) (*ArchivedDelivery, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var d ArchivedDelivery
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, err
+	}
+
+	if d.ID == "" {
+		d.ID, _ = headerValue(d.Headers, "X-GitHub-Delivery")
+	}
+
+	if eventName, ok := headerValue(d.Headers, "X-GitHub-Event"); ok && d.Event == "" {
+		d.Event = Event(eventName)
+	}
+
+	return &d, nil
+}
@@ -0,0 +1,234 @@
+package ghhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDirArchive(t *testing.T) {
+	Convey("DirArchive", t, func() {
+		dir, _ := ioutil.TempDir("", "ghhook-archive")
+		Reset(func() { os.RemoveAll(dir) })
+
+		archive, err := NewDirArchive(dir)
+		So(err, ShouldBeNil)
+
+		day := time.Date(2018, 4, 9, 0, 0, 0, 0, time.UTC)
+		store := func(id string, event Event, at time.Time) {
+			So(archive.Store(&ArchivedDelivery{
+				ID:         id,
+				Event:      event,
+				ReceivedAt: at,
+				Delivery:   Delivery{Headers: map[string]string{"X-GitHub-Event": string(event)}, Body: `{"id":"` + id + `"}`},
+			}), ShouldBeNil)
+		}
+
+		store("b", PushEvent, day.Add(2*time.Hour))
+		store("a", PullRequestEvent, day.Add(time.Hour))
+		store("c", PushEvent, day.Add(25*time.Hour))
+
+		Convey("It lays out deliveries by date and event", func() {
+			_, err := os.Stat(filepath.Join(dir, "2018", "04", "09", "push", "b.json"))
+			So(err, ShouldBeNil)
+			_, err = os.Stat(filepath.Join(dir, "2018", "04", "10", "push", "c.json"))
+			So(err, ShouldBeNil)
+		})
+
+		Convey("It loads deliveries by id and date", func() {
+			d, err := archive.Load("a", day.Add(12*time.Hour))
+
+			So(err, ShouldBeNil)
+			So(d.Event, ShouldEqual, PullRequestEvent)
+			So(d.Body, ShouldEqual, `{"id":"a"}`)
+			So(d.Headers["X-GitHub-Event"], ShouldEqual, "pull_request")
+			So(d.ReceivedAt.Equal(day.Add(time.Hour)), ShouldBeTrue)
+
+			_, err = archive.Load("a", day.Add(24*time.Hour))
+			So(err, ShouldEqual, ErrDeliveryNotArchived)
+
+			_, err = archive.Load("../a", day)
+			So(err, ShouldEqual, ErrInvalidDeliveryID)
+		})
+
+		Convey("It walks deliveries in the order they were received", func() {
+			var ids []string
+			walk := func(from, to time.Time) error {
+				ids = nil
+				return archive.Walk(from, to, func(d *ArchivedDelivery) error {
+					ids = append(ids, d.ID)
+					return nil
+				})
+			}
+
+			So(walk(time.Time{}, time.Time{}), ShouldBeNil)
+			So(ids, ShouldResemble, []string{"a", "b", "c"})
+
+			So(walk(day.Add(90*time.Minute), day.Add(25*time.Hour)), ShouldBeNil)
+			So(ids, ShouldResemble, []string{"b"})
+
+			stop := errors.New("stop")
+			So(archive.Walk(time.Time{}, time.Time{}, func(d *ArchivedDelivery) error { return stop }), ShouldEqual, stop)
+		})
+
+		Convey("It walks deliveries stored on several days once", func() {
+			store("a", PullRequestEvent, day.Add(49*time.Hour))
+
+			var ids []string
+			So(archive.Walk(time.Time{}, time.Time{}, func(d *ArchivedDelivery) error {
+				ids = append(ids, d.ID)
+				So(d.ReceivedAt.Equal(day.Add(time.Hour)), ShouldEqual, d.ID == "a")
+				return nil
+			}), ShouldBeNil)
+			So(ids, ShouldResemble, []string{"a", "b", "c"})
+		})
+
+		Convey("It rejects unsafe event names", func() {
+			err := archive.Store(&ArchivedDelivery{ID: "d", Event: "../push", ReceivedAt: day})
+
+			So(err, ShouldEqual, ErrInvalidEventName)
+		})
+	})
+
+	Convey("DeliveryArchive", t, func() {
+		dir, _ := ioutil.TempDir("", "ghhook-archive")
+		archive, _ := NewDirArchive(dir)
+		DeliveryArchive = archive
+
+		current := time.Date(2018, 4, 9, 10, 0, 0, 0, time.UTC)
+		now = func() time.Time { return current }
+
+		Reset(func() {
+			DeliveryArchive = nil
+			now = time.Now
+			os.RemoveAll(dir)
+		})
+
+		Convey("It archives deliveries of unregistered events", func() {
+			r := &events.APIGatewayProxyRequest{
+				Headers: map[string]string{"X-GitHub-Event": "create", "X-GitHub-Delivery": "d1"},
+				Body:    CreateEventProxyRequest.Body,
+			}
+			DefaultHandler(r)
+
+			d, err := archive.Load("d1", current)
+			So(err, ShouldBeNil)
+			So(d.Event, ShouldEqual, CreateEvent)
+			So(d.Body, ShouldEqual, CreateEventProxyRequest.Body)
+			So(d.Headers, ShouldResemble, r.Headers)
+		})
+
+		Convey("It doesn't archive deliveries rejected as replays", func() {
+			// PullRequestProxyRequest was last updated at 2015-05-05T23:40:27Z.
+			current = time.Date(2015, 5, 5, 23, 45, 0, 0, time.UTC)
+			ReplayProtection = NewReplayGuard(time.Hour, time.Hour)
+			Reset(func() {
+				ReplayProtection = nil
+				ResetHandlers()
+			})
+			EventHandler(PullRequestEvent, func(e interface{}) (*Response, error) { return localSuccessResp("ok") })
+
+			r := &events.APIGatewayProxyRequest{
+				Headers: map[string]string{"X-GitHub-Event": "pull_request", "X-GitHub-Delivery": "d1"},
+				Body:    PullRequestProxyRequest.Body,
+			}
+			DefaultHandler(r)
+
+			current = current.Add(20 * time.Minute)
+			resp, _ := DefaultHandler(r)
+			So(resp.StatusCode, ShouldEqual, 403)
+
+			_, err := archive.Load("d1", current)
+			So(err, ShouldEqual, ErrDeliveryNotArchived)
+		})
+
+		Convey("It replays a delivery redelivered on a later day once", func() {
+			EventHandler(CreateEvent, func(e interface{}) (*Response, error) { return localSuccessResp("ok") })
+			Reset(ResetHandlers)
+
+			r := &events.APIGatewayProxyRequest{
+				Headers: map[string]string{"X-GitHub-Event": "create", "X-GitHub-Delivery": "d1"},
+				Body:    CreateEventProxyRequest.Body,
+			}
+			DefaultHandler(r)
+			current = current.Add(24 * time.Hour)
+			DefaultHandler(r)
+
+			_, err := archive.Load("d1", current)
+			So(err, ShouldBeNil)
+
+			calls := 0
+			So(archive.Walk(time.Time{}, time.Time{}, func(d *ArchivedDelivery) error {
+				_, _, err := ReplayDelivery(d)
+				calls++
+				return err
+			}), ShouldBeNil)
+			So(calls, ShouldEqual, 1)
+		})
+
+		Convey("It fails deliveries that can't be archived", func() {
+			r := &events.APIGatewayProxyRequest{
+				Headers: map[string]string{"X-GitHub-Event": "..", "X-GitHub-Delivery": "d1"},
+				Body:    "{}",
+			}
+			resp, _ := DefaultHandler(r)
+
+			So(resp.StatusCode, ShouldEqual, 500)
+		})
+	})
+}
+
+func TestReplayDelivery(t *testing.T) {
+	Convey("ReplayDelivery", t, func() {
+		var logs, audits bytes.Buffer
+		sink := &recordingSink{}
+		DeliveryLogger = NewJSONLogger(&logs)
+		Metrics = sink
+		AuditLog = NewWriterAuditSink(&audits, false)
+
+		Reset(func() {
+			DeliveryLogger = nil
+			Metrics = nil
+			AuditLog = nil
+			ResetHandlers()
+		})
+
+		EventHandler(PullRequestEvent, func(e interface{}) (*Response, error) { return localSuccessResp("ok") })
+
+		d := &ArchivedDelivery{
+			ID:    "d1",
+			Event: PullRequestEvent,
+			Delivery: Delivery{
+				Headers: map[string]string{"X-GitHub-Delivery": "d1"},
+				Body:    PullRequestProxyRequest.Body,
+			},
+		}
+
+		Convey("It dispatches the delivery", func() {
+			resp, rec, err := ReplayDelivery(d)
+			So(err, ShouldBeNil)
+			So(resp.Body, ShouldEqual, "ok")
+			So(rec.Replay, ShouldBeTrue)
+			So(rec.Outcome, ShouldEqual, OutcomeSucceeded)
+		})
+
+		Convey("It logs replays but doesn't count or audit them", func() {
+			ReplayDelivery(d)
+
+			var m map[string]interface{}
+			So(json.Unmarshal(logs.Bytes(), &m), ShouldBeNil)
+			So(m["replay"], ShouldBeTrue)
+
+			So(sink.metrics, ShouldBeEmpty)
+			So(audits.Len(), ShouldEqual, 0)
+		})
+	})
+}
@@ -0,0 +1,128 @@
+package ghhook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord is the record of a delivery kept by AuditSink.
+type AuditRecord struct {
+	Time       time.Time         `json:"time"`
+	DeliveryID string            `json:"delivery_id"`
+	Event      Event             `json:"event"`
+	Action     string            `json:"action,omitempty"`
+	Repository string            `json:"repository,omitempty"`
+	Sender     string            `json:"sender,omitempty"`
+	Headers    map[string]string `json:"headers"`
+
+	// BodySHA256 is the hex sha256 of the raw body. Body is only set when the
+	// sink includes bodies.
+	BodySHA256 string `json:"body_sha256"`
+	Body       string `json:"body,omitempty"`
+
+	Signature  SignatureStatus `json:"signature"`
+	Handlers   []HandlerResult `json:"handlers"`
+	Outcome    Outcome         `json:"outcome"`
+	StatusCode int             `json:"status_code"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// AuditSink keeps a record of every delivery received by DefaultHandler.
+type AuditSink interface {
+	Audit(rec *AuditRecord) error
+}
+
+// AuditLog is called by DefaultHandler after every delivery is dispatched.
+// It's disabled when nil.
+//
+// The delivery was already handled, so errors of the sink don't change its
+// response; they are set in DeliveryRecord.AuditError, logged by
+// DeliveryLogger, and recorded on the span of the delivery.
+var AuditLog AuditSink
+
+func newAuditRecord(rec *DeliveryRecord) *AuditRecord {
+	sum := sha256.Sum256(rec.payload.body)
+
+	handlers := rec.Handlers
+	if handlers == nil {
+		handlers = []HandlerResult{}
+	}
+
+	return &AuditRecord{
+		Time:       rec.Start,
+		DeliveryID: rec.DeliveryID,
+		Event:      rec.Event,
+		Action:     rec.Action,
+		Repository: rec.Repository,
+		Sender:     rec.Sender,
+		Headers:    rec.headers,
+		BodySHA256: hex.EncodeToString(sum[:]),
+		Body:       string(rec.payload.body),
+		Signature:  rec.Signature,
+		Handlers:   handlers,
+		Outcome:    rec.Outcome,
+		StatusCode: rec.StatusCode,
+		Error:      rec.Error,
+	}
+}
+
+// WriterAuditSink is an AuditSink that writes a JSON object per delivery to
+// Writer, one per line. Bodies are redacted unless IncludeBody is set; the
+// hash of the body is always written.
+type WriterAuditSink struct {
+	Writer      io.Writer
+	IncludeBody bool
+
+	mu sync.Mutex
+}
+
+// NewWriterAuditSink returns a WriterAuditSink that writes to w.
+func NewWriterAuditSink(w io.Writer, includeBody bool) *WriterAuditSink {
+	return &WriterAuditSink{Writer: w, IncludeBody: includeBody}
+}
+
+func (s *WriterAuditSink) Audit(rec *AuditRecord) error {
+	r := *rec
+	if !s.IncludeBody {
+		r.Body = ""
+	}
+
+	b, err := json.Marshal(&r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.Writer.Write(append(b, '\n'))
+	return err
+}
+
+// FileAuditSink is a WriterAuditSink that appends JSON lines to a file.
+type FileAuditSink struct {
+	*WriterAuditSink
+
+	file *os.File
+}
+
+// NewFileAuditSink opens the file at path for appending, creating it if it
+// doesn't exist.
+func NewFileAuditSink(path string, includeBody bool) (*FileAuditSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileAuditSink{WriterAuditSink: NewWriterAuditSink(f, includeBody), file: f}, nil
+}
+
+// Close closes the file.
+func (s *FileAuditSink) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,137 @@
+package ghhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAudit(t *testing.T) {
+	Convey("AuditLog", t, func() {
+		var buf bytes.Buffer
+		sink := NewWriterAuditSink(&buf, false)
+		AuditLog = sink
+		Reset(func() {
+			AuditLog = nil
+			ResetHandlers()
+		})
+
+		decode := func() map[string]interface{} {
+			var m map[string]interface{}
+			So(json.Unmarshal(buf.Bytes(), &m), ShouldBeNil)
+			return m
+		}
+
+		Convey("It records the delivery without its body", func() {
+			On(PullRequestEvent, PullRequestClosed).Handle(func(e interface{}) (*Response, error) { return localSuccessResp("ok") })
+			DefaultHandler(PullRequestProxyRequest)
+
+			m := decode()
+			So(m["event"], ShouldEqual, "pull_request")
+			So(m["action"], ShouldEqual, "opened")
+			So(m["repository"], ShouldEqual, "baxterthehacker/public-repo")
+			So(m["headers"].(map[string]interface{})["X-GitHub-Event"], ShouldEqual, "pull_request")
+			So(m["body_sha256"], ShouldHaveLength, 64)
+			So(m, ShouldNotContainKey, "body")
+			So(m["signature"], ShouldEqual, "unchecked")
+			So(m["outcome"], ShouldEqual, "dropped_filtered")
+			So(m["status_code"], ShouldEqual, 200)
+			So(m["handlers"].([]interface{})[0].(map[string]interface{})["outcome"], ShouldEqual, "dropped")
+		})
+
+		Convey("It includes the body when configured", func() {
+			sink.IncludeBody = true
+			DefaultHandler(CreateEventProxyRequest)
+
+			m := decode()
+			So(m["body"], ShouldEqual, CreateEventProxyRequest.Body)
+			So(m["handlers"], ShouldBeEmpty)
+		})
+
+		Convey("It records the signature validity", func() {
+			WebhookSecret = "secret"
+			Reset(func() { WebhookSecret = "" })
+
+			headers := map[string]string{"X-GitHub-Event": "create", "X-Hub-Signature-256": "sha256=bad"}
+			DefaultHandler(&events.APIGatewayProxyRequest{Headers: headers, Body: "{}"})
+
+			m := decode()
+			So(m["signature"], ShouldEqual, "invalid")
+			So(m["outcome"], ShouldEqual, "rejected")
+			So(m["status_code"], ShouldEqual, 401)
+		})
+	})
+
+	Convey("AuditLog errors", t, func() {
+		var logs bytes.Buffer
+		DeliveryLogger = NewJSONLogger(&logs)
+		AuditLog = NewWriterAuditSink(failingWriter{}, false)
+		Reset(func() {
+			DeliveryLogger = nil
+			AuditLog = nil
+			ResetHandlers()
+		})
+		EventHandler(CreateEvent, func(e interface{}) (*Response, error) { return localSuccessResp("ok") })
+
+		Convey("They don't change the response and are logged", func() {
+			resp, err := DefaultHandler(CreateEventProxyRequest)
+
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 200)
+
+			var m map[string]interface{}
+			So(json.Unmarshal(logs.Bytes(), &m), ShouldBeNil)
+			So(m["level"], ShouldEqual, "ERROR")
+			So(m["outcome"], ShouldEqual, "succeeded")
+			So(m["audit_error"], ShouldEqual, "disk full")
+		})
+
+		Convey("They are logged for closed files", func() {
+			dir, _ := ioutil.TempDir("", "ghhook-audit")
+			Reset(func() { os.RemoveAll(dir) })
+			sink, err := NewFileAuditSink(filepath.Join(dir, "audit.jsonl"), false)
+			So(err, ShouldBeNil)
+			So(sink.Close(), ShouldBeNil)
+			AuditLog = sink
+
+			DefaultHandler(CreateEventProxyRequest)
+
+			var m map[string]interface{}
+			So(json.Unmarshal(logs.Bytes(), &m), ShouldBeNil)
+			So(m["audit_error"], ShouldContainSubstring, "file already closed")
+		})
+	})
+
+	Convey("FileAuditSink appends JSON lines", t, func() {
+		dir, _ := ioutil.TempDir("", "ghhook-audit")
+		Reset(func() { os.RemoveAll(dir) })
+		path := filepath.Join(dir, "audit.jsonl")
+
+		for i := 0; i < 2; i++ {
+			sink, err := NewFileAuditSink(path, false)
+			So(err, ShouldBeNil)
+			So(sink.Audit(&AuditRecord{DeliveryID: "id", Body: "secret"}), ShouldBeNil)
+			So(sink.Close(), ShouldBeNil)
+		}
+
+		b, _ := ioutil.ReadFile(path)
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		So(len(lines), ShouldEqual, 2)
+		So(lines[0], ShouldContainSubstring, `"delivery_id":"id"`)
+		So(lines[0], ShouldNotContainSubstring, "secret")
+	})
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
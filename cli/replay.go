@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/WalkerAndCoBrandsInc/ghhook"
+)
+
+// replayFilter selects the deliveries to replay.
+type replayFilter struct {
+	events listFlag
+	repos  listFlag
+	ids    listFlag
+	since  timeFlag
+	until  timeFlag
+}
+
+// deliveryFields are the fields of the payload printed and filtered on.
+type deliveryFields struct {
+	Action     string `json:"action"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+func (f *replayFilter) match(d *ghhook.ArchivedDelivery, fields *deliveryFields) bool {
+	if len(f.events) > 0 && !f.events.contains(string(d.Event)) {
+		return false
+	}
+
+	if len(f.ids) > 0 && !f.ids.contains(d.ID) {
+		return false
+	}
+
+	if len(f.repos) > 0 && !f.repos.contains(fields.Repository.FullName) {
+		return false
+	}
+
+	if !f.since.IsZero() && d.ReceivedAt.Before(f.since.Time) {
+		return false
+	}
+
+	return f.until.IsZero() || d.ReceivedAt.Before(f.until.Time)
+}
+
+func replayCommand(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("replay", stderr)
+	archiveDir := fs.String("archive", "", "directory of a ghhook.DirArchive")
+	dir := fs.String("dir", "", "directory of delivery JSON files, read recursively")
+	live := fs.Bool("live", false, "run the handlers; by default deliveries are only listed")
+
+	var filter replayFilter
+	fs.Var(&filter.events, "event", "only replay these events, comma separated")
+	fs.Var(&filter.repos, "repo", "only replay deliveries of these repositories, ie 'owner/name', comma separated")
+	fs.Var(&filter.ids, "id", "only replay these delivery ids, comma separated")
+	fs.Var(&filter.since, "since", "only replay deliveries received at or after this time, RFC3339 or YYYY-MM-DD")
+	fs.Var(&filter.until, "until", "only replay deliveries received before this time, RFC3339 or YYYY-MM-DD")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if (*archiveDir == "") == (*dir == "") {
+		fmt.Fprintln(stderr, "ghhook replay: exactly one of -archive or -dir is required")
+		fs.Usage()
+		return 2
+	}
+
+	var matched, failed int
+	replay := func(d *ghhook.ArchivedDelivery) error {
+		var fields deliveryFields
+		json.Unmarshal([]byte(d.Body), &fields)
+
+		if !filter.match(d, &fields) {
+			return nil
+		}
+		matched++
+
+		fmt.Fprintf(stdout, "%s %s %s", d.ReceivedAt.UTC().Format(time.RFC3339), d.ID, d.Event)
+		if fields.Action != "" {
+			fmt.Fprintf(stdout, " %s", fields.Action)
+		}
+		if fields.Repository.FullName != "" {
+			fmt.Fprintf(stdout, " %s", fields.Repository.FullName)
+		}
+
+		if !*live {
+			fmt.Fprintf(stdout, ": %d handlers\n", len(ghhook.Handlers[d.Event]))
+			return nil
+		}
+
+		if !printReplay(stdout, d) {
+			failed++
+		}
+
+		return nil
+	}
+
+	var err error
+	if *archiveDir != "" {
+		err = replayArchive(*archiveDir, &filter, replay)
+	} else {
+		err = replayDir(*dir, replay)
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "ghhook replay: %v\n", err)
+		return 1
+	}
+
+	if *live {
+		fmt.Fprintf(stdout, "replayed %d deliveries, %d failed\n", matched, failed)
+	} else {
+		fmt.Fprintf(stdout, "%d deliveries to replay, run with -live to replay them\n", matched)
+	}
+
+	if failed > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// printReplay replays the delivery and prints the response of each InputFn.
+// It returns false if the delivery failed.
+func printReplay(w io.Writer, d *ghhook.ArchivedDelivery) bool {
+	resp, rec, err := ghhook.ReplayDelivery(d)
+	if err != nil {
+		fmt.Fprintf(w, ": %v\n", err)
+		return false
+	}
+
+	fmt.Fprintf(w, ": %s %d\n", rec.Outcome, resp.StatusCode)
+	if rec.Error != "" {
+		fmt.Fprintf(w, "  error: %s\n", rec.Error)
+	}
+
+	printHandlers(w, rec.Handlers)
+
+	return rec.Outcome != ghhook.OutcomeFailed && rec.Outcome != ghhook.OutcomeRejected
+}
+
+func replayArchive(dir string, filter *replayFilter, fn func(d *ghhook.ArchivedDelivery) error) error {
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+
+	archive, err := ghhook.NewDirArchive(dir)
+	if err != nil {
+		return err
+	}
+
+	return archive.Walk(filter.since.Time, filter.until.Time, fn)
+}
+
+// replayDir reads the JSON files of dir, archived deliveries or plain
+// ghhook.Delivery, and replays them in the order they were received, then by
+// path. Like ghhook.DirArchive, deliveries found more than once are only
+// replayed the first time they were received.
+func replayDir(dir string, fn func(d *ghhook.ArchivedDelivery) error) error {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && strings.HasSuffix(path, ".json") {
+			paths = append(paths, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	deliveries := make([]*ghhook.ArchivedDelivery, 0, len(paths))
+	for _, path := range paths {
+		d, err := ghhook.ReadArchivedDelivery(path)
+		if err != nil {
+			return fmt.Errorf("reading '%s': %v", path, err)
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].ReceivedAt.Before(deliveries[j].ReceivedAt)
+	})
+
+	seen := map[string]bool{}
+	for _, d := range deliveries {
+		if d.ID != "" && seen[d.ID] {
+			continue
+		}
+		seen[d.ID] = true
+
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printHandlers prints the result of each InputFn, with its response when it
+// succeeded.
+func printHandlers(w io.Writer, handlers []ghhook.HandlerResult) {
+	for _, h := range handlers {
+		fmt.Fprintf(w, "  handler %d: %s", h.Index, h.Outcome)
+
+		switch {
+		case h.Outcome == ghhook.HandlerFailed:
+			fmt.Fprintf(w, ": %s", h.Error)
+		case h.Outcome == ghhook.HandlerDropped:
+			fmt.Fprintf(w, ": %s", h.Reason)
+		case h.Response != nil:
+			fmt.Fprintf(w, ": %d %s", h.Response.StatusCode, h.Response.Body)
+		}
+
+		fmt.Fprintf(w, " (%s)\n", h.Duration)
+	}
+}
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WalkerAndCoBrandsInc/ghhook"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReplay(t *testing.T) {
+	Convey("ghhook replay", t, func() {
+		dir, _ := ioutil.TempDir("", "ghhook-replay")
+		Reset(func() {
+			os.RemoveAll(dir)
+			ghhook.ResetHandlers()
+		})
+
+		archive, err := ghhook.NewDirArchive(dir)
+		So(err, ShouldBeNil)
+
+		day := time.Date(2018, 4, 9, 0, 0, 0, 0, time.UTC)
+		store := func(id string, event ghhook.Event, repo string, at time.Time) {
+			So(archive.Store(&ghhook.ArchivedDelivery{
+				ID:         id,
+				Event:      event,
+				ReceivedAt: at,
+				Delivery: ghhook.Delivery{
+					Headers: map[string]string{"X-GitHub-Event": string(event), "X-GitHub-Delivery": id},
+					Body:    `{"action":"opened","ref":"master","ref_type":"branch","repository":{"full_name":"` + repo + `"}}`,
+				},
+			}), ShouldBeNil)
+		}
+
+		store("d1", ghhook.CreateEvent, "owner/api", day.Add(time.Hour))
+		store("d2", ghhook.CreateEvent, "owner/web", day.Add(2*time.Hour))
+		store("d3", ghhook.PushEvent, "owner/api", day.Add(26*time.Hour))
+
+		var calls int
+		ghhook.EventHandler(ghhook.CreateEvent, func(e interface{}) (*ghhook.Response, error) {
+			calls++
+			return &ghhook.Response{StatusCode: 201, Body: "created"}, nil
+		})
+
+		run := func(args ...string) (int, string, string) {
+			var stdout, stderr bytes.Buffer
+			code := Run(append([]string{"replay"}, args...), &stdout, &stderr)
+			return code, stdout.String(), stderr.String()
+		}
+
+		Convey("It lists the deliveries in dry run", func() {
+			code, stdout, _ := run("-archive", dir)
+
+			So(code, ShouldEqual, 0)
+			So(calls, ShouldEqual, 0)
+			So(stdout, ShouldEqual, `2018-04-09T01:00:00Z d1 create opened owner/api: 1 handlers
+2018-04-09T02:00:00Z d2 create opened owner/web: 1 handlers
+2018-04-10T02:00:00Z d3 push opened owner/api: 0 handlers
+3 deliveries to replay, run with -live to replay them
+`)
+		})
+
+		Convey("It filters deliveries", func() {
+			_, stdout, _ := run("-archive", dir, "-repo", "owner/api")
+			So(stdout, ShouldContainSubstring, "2 deliveries")
+
+			_, stdout, _ = run("-archive", dir, "-event", "push")
+			So(stdout, ShouldContainSubstring, "d3")
+			So(stdout, ShouldContainSubstring, "1 deliveries")
+
+			_, stdout, _ = run("-archive", dir, "-since", "2018-04-09T01:30:00Z", "-until", "2018-04-10")
+			So(stdout, ShouldContainSubstring, "d2")
+			So(stdout, ShouldContainSubstring, "1 deliveries")
+
+			_, stdout, _ = run("-archive", dir, "-id", "d1,d3")
+			So(stdout, ShouldContainSubstring, "2 deliveries")
+		})
+
+		Convey("It replays deliveries through the handlers", func() {
+			code, stdout, _ := run("-archive", dir, "-live", "-event", "create")
+
+			So(code, ShouldEqual, 0)
+			So(calls, ShouldEqual, 2)
+			So(stdout, ShouldContainSubstring, "d1 create opened owner/api: succeeded 201\n  handler 0: succeeded: 201 created")
+			So(stdout, ShouldContainSubstring, "replayed 2 deliveries, 0 failed")
+		})
+
+		Convey("It replays deliveries redelivered on a later day once", func() {
+			store("d1", ghhook.CreateEvent, "owner/api", day.Add(50*time.Hour))
+
+			for _, source := range []string{"-archive", "-dir"} {
+				calls = 0
+				code, stdout, _ := run(source, dir, "-live", "-event", "create")
+
+				So(code, ShouldEqual, 0)
+				So(calls, ShouldEqual, 2)
+				So(stdout, ShouldContainSubstring, "replayed 2 deliveries, 0 failed")
+			}
+		})
+
+		Convey("It fails when handlers fail", func() {
+			ghhook.EventHandler(ghhook.CreateEvent, func(e interface{}) (*ghhook.Response, error) {
+				return nil, errors.New("boom")
+			})
+
+			code, stdout, _ := run("-archive", dir, "-live", "-id", "d1")
+
+			So(code, ShouldEqual, 1)
+			So(stdout, ShouldContainSubstring, "  handler 1: failed: boom")
+			So(stdout, ShouldContainSubstring, "replayed 1 deliveries, 1 failed")
+		})
+
+		Convey("It reads a directory of delivery files", func() {
+			files, _ := ioutil.TempDir("", "ghhook-replay-files")
+			Reset(func() { os.RemoveAll(files) })
+			ioutil.WriteFile(filepath.Join(files, "create.json"), []byte(`{"headers":{"X-GitHub-Event":"create","X-GitHub-Delivery":"f1"},"payload":{"ref":"master","ref_type":"branch"}}`), 0644)
+
+			code, stdout, _ := run("-dir", files, "-live")
+
+			So(code, ShouldEqual, 0)
+			So(calls, ShouldEqual, 1)
+			So(stdout, ShouldContainSubstring, "f1 create: succeeded 201")
+		})
+
+		Convey("It requires a source", func() {
+			code, _, stderr := run()
+
+			So(code, ShouldEqual, 2)
+			So(stderr, ShouldContainSubstring, "exactly one of -archive or -dir")
+		})
+	})
+
+	Convey("Run rejects unknown commands", t, func() {
+		var stderr bytes.Buffer
+
+		So(Run([]string{"nope"}, &bytes.Buffer{}, &stderr), ShouldEqual, 2)
+		So(stderr.String(), ShouldContainSubstring, "unknown command 'nope'")
+	})
+}
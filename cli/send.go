@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/WalkerAndCoBrandsInc/ghhook"
+	"github.com/WalkerAndCoBrandsInc/ghhook/ghhooktest"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func sendCommand(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("send", stderr)
+	url := fs.String("url", "", "URL to POST the delivery to; by default the APIGatewayProxyRequest event is written to stdout, ie for 'sam local invoke'")
+	secret := fs.String("secret", os.Getenv("GHHOOK_SECRET"), "secret to sign the delivery with, defaults to $GHHOOK_SECRET")
+	deliveryID := fs.String("delivery", "", "delivery id, a random UUID by default")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: ghhook send [flags] <event> [payload.json]")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Without a payload file, the built-in fixture of the event is sent.")
+		fmt.Fprintln(stderr)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return 2
+	}
+
+	event := ghhook.Event(fs.Arg(0))
+
+	var body interface{}
+	if fs.NArg() == 2 {
+		b, err := ioutil.ReadFile(fs.Arg(1))
+		if err != nil {
+			fmt.Fprintf(stderr, "ghhook send: %v\n", err)
+			return 1
+		}
+		body = b
+	} else {
+		if !hasFixture(event) {
+			fmt.Fprintf(stderr, "ghhook send: no built-in fixture for event '%s'\n", event)
+			return 1
+		}
+		body = ghhooktest.Fixture(event)
+	}
+
+	opts := []ghhooktest.RequestOption{ghhooktest.WithSecret(*secret), ghhooktest.WithHeader("User-Agent", "GitHub-Hookshot/ghhook")}
+	if *deliveryID != "" {
+		opts = append(opts, ghhooktest.WithDeliveryID(*deliveryID))
+	}
+
+	req := ghhooktest.NewProxyRequest(event, body, opts...)
+
+	if *url == "" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(req); err != nil {
+			fmt.Fprintf(stderr, "ghhook send: %v\n", err)
+			return 1
+		}
+
+		return 0
+	}
+
+	return postDelivery(*url, req, stdout, stderr)
+}
+
+func hasFixture(event ghhook.Event) bool {
+	for _, e := range ghhooktest.Events() {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// postDelivery posts the request and prints the response. It returns 1 if the
+// response has an error status.
+func postDelivery(url string, req *events.APIGatewayProxyRequest, stdout, stderr io.Writer) int {
+	httpReq, err := http.NewRequest(http.MethodPost, url, strings.NewReader(req.Body))
+	if err != nil {
+		fmt.Fprintf(stderr, "ghhook send: %v\n", err)
+		return 1
+	}
+
+	for k, v := range req.Headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := (&http.Client{Timeout: forwardTimeout}).Do(httpReq)
+	if err != nil {
+		fmt.Fprintf(stderr, "ghhook send: %v\n", err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	fmt.Fprintf(stdout, "%s %s: %s\n%s\n", req.Headers["X-GitHub-Delivery"], req.Headers["X-GitHub-Event"], resp.Status, respBody)
+
+	if resp.StatusCode >= 400 {
+		return 1
+	}
+
+	return 0
+}
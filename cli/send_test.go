@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/WalkerAndCoBrandsInc/ghhook"
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSend(t *testing.T) {
+	Convey("ghhook send", t, func() {
+		run := func(args ...string) (int, string, string) {
+			var stdout, stderr bytes.Buffer
+			code := Run(append([]string{"send"}, args...), &stdout, &stderr)
+			return code, stdout.String(), stderr.String()
+		}
+
+		Convey("It writes the signed APIGatewayProxyRequest of a fixture", func() {
+			code, stdout, _ := run("-secret", "secret", "pull_request")
+			So(code, ShouldEqual, 0)
+
+			var req events.APIGatewayProxyRequest
+			So(json.Unmarshal([]byte(stdout), &req), ShouldBeNil)
+			So(req.Headers["X-GitHub-Event"], ShouldEqual, "pull_request")
+			So(req.Headers["X-GitHub-Delivery"], ShouldHaveLength, 36)
+			So(ghhook.VerifySignature("secret", req.Headers, req.Body), ShouldBeNil)
+			So(req.Headers["X-Hub-Signature"], ShouldEqual, ghhook.SignSHA1("secret", req.Body))
+			So(req.Body, ShouldContainSubstring, `"action":"opened"`)
+
+			Convey("Which DefaultHandler accepts", func() {
+				ghhook.WebhookSecret = "secret"
+				ghhook.EventHandler(ghhook.PullRequestEvent, func(e interface{}) (*ghhook.Response, error) {
+					return &ghhook.Response{StatusCode: 200, Body: "ok"}, nil
+				})
+				Reset(func() {
+					ghhook.WebhookSecret = ""
+					ghhook.ResetHandlers()
+				})
+
+				resp, err := ghhook.DefaultHandler(&req)
+				So(err, ShouldBeNil)
+				So(resp.Body, ShouldEqual, "ok")
+			})
+		})
+
+		Convey("It posts a payload file", func() {
+			dir, _ := ioutil.TempDir("", "ghhook-send")
+			Reset(func() { os.RemoveAll(dir) })
+			path := filepath.Join(dir, "push.json")
+			ioutil.WriteFile(path, []byte(`{"ref":"refs/heads/master"}`), 0644)
+
+			var received *http.Request
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r
+				body, _ = ioutil.ReadAll(r.Body)
+				w.Write([]byte("handled"))
+			}))
+			Reset(server.Close)
+
+			code, stdout, _ := run("-url", server.URL, "-delivery", "d1", "push", path)
+
+			So(code, ShouldEqual, 0)
+			So(stdout, ShouldEqual, "d1 push: 200 OK\nhandled\n")
+			So(string(body), ShouldEqual, `{"ref":"refs/heads/master"}`)
+			So(received.Header.Get("X-GitHub-Delivery"), ShouldEqual, "d1")
+			So(received.Header.Get("X-Hub-Signature"), ShouldEqual, "")
+		})
+
+		Convey("It fails on error responses", func() {
+			server := httptest.NewServer(http.NotFoundHandler())
+			Reset(server.Close)
+
+			code, stdout, _ := run("-url", server.URL, "create")
+
+			So(code, ShouldEqual, 1)
+			So(stdout, ShouldContainSubstring, "404 Not Found")
+		})
+
+		Convey("It requires a fixture or a payload file", func() {
+			code, _, stderr := run("check_run")
+			So(code, ShouldEqual, 1)
+			So(stderr, ShouldContainSubstring, "no built-in fixture for event 'check_run'")
+
+			code, _, _ = run()
+			So(code, ShouldEqual, 2)
+		})
+	})
+}
@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/WalkerAndCoBrandsInc/ghhook"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var (
+	// forwardTimeout is the timeout of requests forwarded by serve and posted
+	// by send.
+	forwardTimeout = 30 * time.Second
+
+	// now is used instead of time.Now so tests can control the clock.
+	now = time.Now
+)
+
+// textLogger is a ghhook.Logger that writes readable lines for local
+// development.
+type textLogger struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+func (l *textLogger) LogDelivery(rec *ghhook.DeliveryRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fmt.Fprintf(l.w, "%s %s %s", rec.Start.Format("15:04:05"), rec.DeliveryID, rec.Event)
+	if rec.Action != "" {
+		fmt.Fprintf(l.w, " %s", rec.Action)
+	}
+	if rec.Repository != "" {
+		fmt.Fprintf(l.w, " %s", rec.Repository)
+	}
+	if rec.Sender != "" {
+		fmt.Fprintf(l.w, " by %s", rec.Sender)
+	}
+
+	fmt.Fprintf(l.w, ": %s %d, signature %s (%s)\n", rec.Outcome, rec.StatusCode, rec.Signature, rec.Duration)
+	if rec.Error != "" {
+		fmt.Fprintf(l.w, "  error: %s\n", rec.Error)
+	}
+
+	printHandlers(l.w, rec.Handlers)
+}
+
+// printf writes a line prefixed with the time.
+func (l *textLogger) printf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fmt.Fprintf(l.w, "%s "+format+"\n", append([]interface{}{now().Format("15:04:05")}, args...)...)
+}
+
+func serveCommand(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("serve", stderr)
+	addr := fs.String("addr", "localhost:3000", "address to listen on")
+	path := fs.String("path", "/", "path of the webhook")
+	secret := fs.String("secret", os.Getenv("GHHOOK_SECRET"), "secret of the webhook, defaults to $GHHOOK_SECRET")
+	forward := fs.String("forward", "", "URL to forward deliveries to as Lambda APIGatewayProxyRequest events, instead of running the handlers")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *secret == "" {
+		fmt.Fprintln(stderr, "ghhook serve: no secret, signatures are not verified")
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(*path, serveHandler(*secret, *forward, stdout))
+
+	fmt.Fprintf(stdout, "listening on http://%s%s\n", *addr, *path)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		fmt.Fprintf(stderr, "ghhook serve: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// serveHandler returns the handler of the webhook. Deliveries are dispatched
+// to the registered InputFn and logged to w, or forwarded to forwardURL when
+// it's set.
+func serveHandler(secret, forwardURL string, w io.Writer) http.Handler {
+	if forwardURL == "" {
+		ghhook.WebhookSecret = secret
+		ghhook.DeliveryLogger = &textLogger{w: w}
+
+		return ghhook.HTTPHandler()
+	}
+
+	client := &http.Client{Timeout: forwardTimeout}
+	logger := &textLogger{w: w}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		req, err := ghhook.ProxyRequestFromHTTP(r)
+		if err != nil {
+			http.Error(rw, err.Error(), ghhook.StatusCodeOf(err))
+			return
+		}
+
+		deliveryID := req.Headers["X-Github-Delivery"]
+		event := req.Headers["X-Github-Event"]
+
+		if secret != "" {
+			if err := ghhook.VerifySignature(secret, req.Headers, req.Body); err != nil {
+				logger.printf("%s %s: %v", deliveryID, event, err)
+
+				http.Error(rw, err.Error(), ghhook.StatusCodeOf(err))
+				return
+			}
+		}
+
+		resp, err := forwardRequest(client, forwardURL, req)
+		if err != nil {
+			logger.printf("%s %s: forwarding failed: %v", deliveryID, event, err)
+			http.Error(rw, err.Error(), http.StatusBadGateway)
+			return
+		}
+		logger.printf("%s %s: forwarded, %d %s", deliveryID, event, resp.StatusCode, resp.Body)
+
+		for k, v := range resp.Headers {
+			rw.Header().Set(k, v)
+		}
+		rw.WriteHeader(resp.StatusCode)
+		rw.Write([]byte(resp.Body))
+	})
+}
+
+// forwardRequest posts the request as a Lambda event, ie to the invoke
+// endpoint of 'sam local start-lambda', and decodes the response of the
+// remote DefaultHandler.
+func forwardRequest(client *http.Client, url string, req *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if httpResp.StatusCode >= 300 {
+		return nil, fmt.Errorf("status %d: %s", httpResp.StatusCode, body)
+	}
+
+	var resp events.APIGatewayProxyResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("decoding response: %v", err)
+	}
+
+	if resp.StatusCode == 0 {
+		resp.StatusCode = http.StatusOK
+	}
+
+	return &resp, nil
+}
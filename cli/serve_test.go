@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WalkerAndCoBrandsInc/ghhook"
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestServe(t *testing.T) {
+	body := `{"ref":"master","ref_type":"branch","repository":{"full_name":"owner/api"},"sender":{"login":"octocat"}}`
+
+	post := func(url, secret string) (*http.Response, string) {
+		req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		req.Header.Set("X-GitHub-Event", "create")
+		req.Header.Set("X-GitHub-Delivery", "d1")
+		if secret != "" {
+			req.Header.Set("X-Hub-Signature-256", ghhook.SignSHA256(secret, body))
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		So(err, ShouldBeNil)
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp, string(b)
+	}
+
+	Convey("ghhook serve", t, func() {
+		now = func() time.Time { return time.Date(2018, 4, 9, 10, 0, 0, 0, time.UTC) }
+		Reset(func() {
+			now = time.Now
+			ghhook.WebhookSecret = ""
+			ghhook.DeliveryLogger = nil
+			ghhook.ResetHandlers()
+		})
+
+		var logs bytes.Buffer
+
+		Convey("It runs the handlers and logs deliveries", func() {
+			ghhook.EventHandler(ghhook.CreateEvent, func(e interface{}) (*ghhook.Response, error) {
+				return &ghhook.Response{StatusCode: 200, Body: "created"}, nil
+			})
+
+			server := httptest.NewServer(serveHandler("secret", "", &logs))
+			Reset(server.Close)
+
+			resp, respBody := post(server.URL, "secret")
+			So(resp.StatusCode, ShouldEqual, 200)
+			So(respBody, ShouldEqual, "created")
+			So(logs.String(), ShouldContainSubstring, "d1 create owner/api by octocat: succeeded 200, signature valid")
+			So(logs.String(), ShouldContainSubstring, "  handler 0: succeeded: 200 created")
+
+			resp, _ = post(server.URL, "other")
+			So(resp.StatusCode, ShouldEqual, 401)
+			So(logs.String(), ShouldContainSubstring, "rejected 401, signature invalid")
+		})
+
+		Convey("It forwards deliveries as Lambda events", func() {
+			var received events.APIGatewayProxyRequest
+			lambda := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&received)
+				json.NewEncoder(w).Encode(&events.APIGatewayProxyResponse{StatusCode: 202, Body: "remote"})
+			}))
+			Reset(lambda.Close)
+
+			server := httptest.NewServer(serveHandler("secret", lambda.URL, &logs))
+			Reset(server.Close)
+
+			resp, respBody := post(server.URL, "secret")
+			So(resp.StatusCode, ShouldEqual, 202)
+			So(respBody, ShouldEqual, "remote")
+			So(received.Body, ShouldEqual, body)
+			So(received.Headers["X-Github-Event"], ShouldEqual, "create")
+			So(logs.String(), ShouldEqual, "10:00:00 d1 create: forwarded, 202 remote\n")
+
+			Convey("It doesn't forward deliveries with an invalid signature", func() {
+				received = events.APIGatewayProxyRequest{}
+				resp, _ := post(server.URL, "")
+
+				So(resp.StatusCode, ShouldEqual, 401)
+				So(received.Body, ShouldEqual, "")
+			})
+		})
+
+		Convey("It reports unreachable forward URLs", func() {
+			lambda := httptest.NewServer(http.NotFoundHandler())
+			Reset(lambda.Close)
+
+			server := httptest.NewServer(serveHandler("", lambda.URL, &logs))
+			Reset(server.Close)
+
+			resp, _ := post(server.URL, "")
+			So(resp.StatusCode, ShouldEqual, 502)
+			So(logs.String(), ShouldContainSubstring, "forwarding failed: status 404")
+		})
+	})
+}
@@ -0,0 +1,14 @@
+// Command ghhook is the ghhook command line without any handlers registered.
+// Projects that replay deliveries through their handlers build their own
+// binary with the cli package.
+package main
+
+import (
+	"os"
+
+	"github.com/WalkerAndCoBrandsInc/ghhook/cli"
+)
+
+func main() {
+	os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+}
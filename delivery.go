@@ -0,0 +1,117 @@
+package ghhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Delivery is a Github webhook delivery that was republished over another
+// transport, ie SNS or EventBridge. It carries the original request headers so
+// it can be dispatched the same way as a request from APIGateway.
+//
+// The raw body can be given either as a string in Body or as a JSON object in
+// Payload; Body takes precedence when both are set.
+type Delivery struct {
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body,omitempty"`
+	Payload json.RawMessage   `json:"payload,omitempty"`
+}
+
+var (
+	// ErrEmptyDelivery is returned when a republished delivery has no body.
+	ErrEmptyDelivery = errors.New("ERROR: delivery has no body")
+)
+
+// ProxyRequest converts the delivery into the APIGatewayProxyRequest received
+// by DefaultHandler.
+func (d *Delivery) ProxyRequest() (*events.APIGatewayProxyRequest, error) {
+	body := d.Body
+	if body == "" {
+		body = string(d.Payload)
+	}
+
+	if body == "" {
+		return nil, ErrEmptyDelivery
+	}
+
+	return &events.APIGatewayProxyRequest{
+		Headers: d.Headers,
+		Body:    body,
+	}, nil
+}
+
+// SNSHandler is a Lambda compatible handler that receives SNS notifications
+// whose messages are JSON encoded Delivery and dispatches each of them through
+// DefaultHandler.
+//
+// It returns an error if any of the records could not be decoded or if
+// DefaultHandler responded with an error status, so SNS can retry.
+func SNSHandler(e *events.SNSEvent) error {
+	for _, record := range e.Records {
+		var d Delivery
+		if err := json.Unmarshal([]byte(record.SNS.Message), &d); err != nil {
+			return fmt.Errorf("ERROR: decoding SNS message '%s': %v", record.SNS.MessageID, err)
+		}
+
+		if err := dispatchDelivery(&d); err != nil {
+			return fmt.Errorf("ERROR: SNS message '%s': %v", record.SNS.MessageID, err)
+		}
+	}
+
+	return nil
+}
+
+// EventBridgeHandler is a Lambda compatible handler that receives EventBridge
+// (CloudWatch Events) envelopes whose detail is a Delivery and dispatches it
+// through DefaultHandler.
+func EventBridgeHandler(e *events.CloudWatchEvent) error {
+	var d Delivery
+	if err := json.Unmarshal(e.Detail, &d); err != nil {
+		return fmt.Errorf("ERROR: decoding EventBridge event '%s': %v", e.ID, err)
+	}
+
+	if err := dispatchDelivery(&d); err != nil {
+		return fmt.Errorf("ERROR: EventBridge event '%s': %v", e.ID, err)
+	}
+
+	return nil
+}
+
+func dispatchDelivery(d *Delivery) error {
+	r, err := d.ProxyRequest()
+	if err != nil {
+		return err
+	}
+
+	resp, err := DefaultHandler(r)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	return nil
+}
+
+// headerValue returns the value of the header with the given key. Header names
+// are case insensitive, and transports other than APIGateway don't always
+// preserve Github's casing.
+func headerValue(headers map[string]string, key string) (string, bool) {
+	if v, ok := headers[key]; ok {
+		return v, true
+	}
+
+	for k, v := range headers {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,380 @@
+package ghhook
+
+import (
+	"container/list"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// DeliveryStore keeps track of processed webhook deliveries, keyed by the
+// 'X-GitHub-Delivery' header, so redelivered webhooks are only handled once.
+type DeliveryStore interface {
+	// Seen returns true if the delivery is claimed or completed and has not
+	// expired.
+	Seen(id string) (bool, error)
+
+	// Claim marks the delivery as in progress for the given ttl. It returns
+	// false if the delivery was already claimed or completed.
+	Claim(id string, ttl time.Duration) (bool, error)
+
+	// Complete marks the delivery as processed for the given ttl.
+	Complete(id string, ttl time.Duration) error
+
+	// Release removes the claim on a delivery, so it can be retried.
+	Release(id string) error
+}
+
+var (
+	// Deliveries is used by DefaultHandler to skip deliveries that were already
+	// processed. Deduplication is disabled when it's nil.
+	Deliveries DeliveryStore
+
+	// DeliveryClaimTTL is how long a delivery is claimed while its InputFn are
+	// running.
+	DeliveryClaimTTL = 5 * time.Minute
+
+	// DeliveryTTL is how long a processed delivery is remembered.
+	DeliveryTTL = 24 * time.Hour
+
+	// ErrInvalidDeliveryID is returned by FileDeliveryStore for delivery ids
+	// that can't be safely used as file names.
+	ErrInvalidDeliveryID = errors.New("ERROR: invalid delivery id")
+
+	// now is used instead of time.Now so tests can control the clock.
+	now = time.Now
+)
+
+// MemoryDeliveryStore is an in-memory DeliveryStore that holds up to a fixed
+// number of deliveries, evicting the least recently used ones.
+type MemoryDeliveryStore struct {
+	size    int
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type memoryDelivery struct {
+	id      string
+	expires time.Time
+}
+
+// NewMemoryDeliveryStore returns a MemoryDeliveryStore that holds up to size
+// deliveries.
+func NewMemoryDeliveryStore(size int) *MemoryDeliveryStore {
+	return &MemoryDeliveryStore{
+		size:    size,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+func (s *MemoryDeliveryStore) Seen(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lookup(id) != nil, nil
+}
+
+func (s *MemoryDeliveryStore) Claim(id string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lookup(id) != nil {
+		return false, nil
+	}
+
+	s.set(id, ttl)
+	return true, nil
+}
+
+func (s *MemoryDeliveryStore) Complete(id string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.set(id, ttl)
+	return nil
+}
+
+func (s *MemoryDeliveryStore) Release(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[id]; ok {
+		s.remove(el)
+	}
+
+	return nil
+}
+
+// lookup returns the unexpired entry for id and marks it as recently used.
+func (s *MemoryDeliveryStore) lookup(id string) *list.Element {
+	el, ok := s.entries[id]
+	if !ok {
+		return nil
+	}
+
+	if now().After(el.Value.(*memoryDelivery).expires) {
+		s.remove(el)
+		return nil
+	}
+
+	s.order.MoveToFront(el)
+	return el
+}
+
+func (s *MemoryDeliveryStore) set(id string, ttl time.Duration) {
+	expires := now().Add(ttl)
+
+	if el, ok := s.entries[id]; ok {
+		el.Value.(*memoryDelivery).expires = expires
+		s.order.MoveToFront(el)
+		return
+	}
+
+	s.entries[id] = s.order.PushFront(&memoryDelivery{id: id, expires: expires})
+
+	for s.size > 0 && s.order.Len() > s.size {
+		s.remove(s.order.Back())
+	}
+}
+
+func (s *MemoryDeliveryStore) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.entries, el.Value.(*memoryDelivery).id)
+}
+
+// FileDeliveryStore is a DeliveryStore that keeps one file per delivery in a
+// directory. It can be shared by processes that have access to the same
+// directory, ie an EFS mount.
+type FileDeliveryStore struct {
+	dir string
+}
+
+type fileDelivery struct {
+	Expires time.Time `json:"expires"`
+}
+
+var deliveryIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// staleClaimLockAge is the age after which the lock file of a claim being
+// taken over is considered left behind by a crashed process.
+const staleClaimLockAge = time.Minute
+
+// NewFileDeliveryStore returns a FileDeliveryStore that keeps its files in dir.
+// The directory is created if it doesn't exist.
+func NewFileDeliveryStore(dir string) (*FileDeliveryStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &FileDeliveryStore{dir: dir}, nil
+}
+
+func (s *FileDeliveryStore) Seen(id string) (bool, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return false, err
+	}
+
+	d, err := readFileDelivery(path)
+	if err != nil || d == nil {
+		return false, err
+	}
+
+	return !now().After(d.Expires), nil
+}
+
+func (s *FileDeliveryStore) Claim(id string, ttl time.Duration) (bool, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return false, err
+	}
+
+	b, err := json.Marshal(&fileDelivery{Expires: now().Add(ttl)})
+	if err != nil {
+		return false, err
+	}
+
+	for {
+		err := createFile(path, b)
+		if err == nil {
+			return true, nil
+		}
+
+		if !os.IsExist(err) {
+			return false, err
+		}
+
+		d, err := readFileDelivery(path)
+		if err != nil {
+			return false, err
+		}
+
+		if d == nil {
+			// the claim was released meanwhile.
+			continue
+		}
+
+		if !now().After(d.Expires) {
+			return false, nil
+		}
+
+		claimed, retry, err := s.takeOver(path, b)
+		if !retry {
+			return claimed, err
+		}
+	}
+}
+
+// takeOver replaces the expired claim at path. Processes taking over the same
+// claim race to create its lock file, and only the one that creates it checks
+// the claim again and replaces it. The lock file holds the new claim and is
+// renamed over the expired one, so readers never see a missing or partial
+// claim. It returns retry when the claim was released meanwhile, or when the
+// lock was removed as stale.
+func (s *FileDeliveryStore) takeOver(path string, b []byte) (claimed, retry bool, err error) {
+	lock := path + ".lock"
+
+	if err := createFile(lock, b); err != nil {
+		if !os.IsExist(err) {
+			return false, false, err
+		}
+
+		// a process that crashed while taking over leaves its lock behind.
+		info, err := os.Stat(lock)
+		if err == nil && now().Sub(info.ModTime()) > staleClaimLockAge {
+			return false, removeStaleLock(lock), nil
+		}
+
+		return false, os.IsNotExist(err), nil
+	}
+
+	d, err := readFileDelivery(path)
+	if err != nil || d == nil || !now().After(d.Expires) {
+		os.Remove(lock)
+		return false, err == nil && d == nil, err
+	}
+
+	if err := os.Rename(lock, path); err != nil {
+		if os.IsNotExist(err) {
+			// the lock was moved away as stale, the claim is checked again.
+			return false, true, nil
+		}
+
+		os.Remove(lock)
+		return false, false, err
+	}
+
+	return true, false, nil
+}
+
+// removeStaleLock moves the stale lock to a unique name before removing it, so
+// processes that find the same stale lock can't remove each other's new lock.
+// A process that moved a new lock instead, because another process removed
+// the stale one first, links it back. It returns true if the lock was moved.
+func removeStaleLock(lock string) bool {
+	moved := fmt.Sprintf("%s.%s", lock, randomToken())
+	if err := os.Rename(lock, moved); err != nil {
+		return os.IsNotExist(err)
+	}
+	defer os.Remove(moved)
+
+	if info, err := os.Stat(moved); err == nil && now().Sub(info.ModTime()) <= staleClaimLockAge {
+		os.Link(moved, lock)
+	}
+
+	return true
+}
+
+// randomToken returns a random hex string, to name files uniquely across
+// processes.
+func randomToken() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+func (s *FileDeliveryStore) Complete(id string, ttl time.Duration) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(&fileDelivery{Expires: now().Add(ttl)})
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so readers never see a partial file.
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func (s *FileDeliveryStore) Release(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *FileDeliveryStore) path(id string) (string, error) {
+	if !deliveryIDRegexp.MatchString(id) || id == "." || id == ".." {
+		return "", ErrInvalidDeliveryID
+	}
+
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+// createFile writes b to a new file at path. It fails if the file already
+// exists.
+func createFile(path string, b []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// readFileDelivery returns nil if the file does not exist.
+func readFileDelivery(path string) (*fileDelivery, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var d fileDelivery
+	if err := json.Unmarshal(b, &d); err != nil {
+		// a claim that's still being written is treated as fresh.
+		return &fileDelivery{Expires: now().Add(DeliveryClaimTTL)}, nil
+	}
+
+	return &d, nil
+}
@@ -0,0 +1,264 @@
+package ghhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDeliveryStores(t *testing.T) {
+	Convey("DeliveryStore", t, func() {
+		current := time.Date(2018, 4, 9, 0, 0, 0, 0, time.UTC)
+		now = func() time.Time { return current }
+		Reset(func() { now = time.Now })
+
+		dir, err := ioutil.TempDir("", "ghhook")
+		So(err, ShouldBeNil)
+		Reset(func() { os.RemoveAll(dir) })
+
+		fileStore, err := NewFileDeliveryStore(dir)
+		So(err, ShouldBeNil)
+
+		stores := map[string]DeliveryStore{
+			"MemoryDeliveryStore": NewMemoryDeliveryStore(10),
+			"FileDeliveryStore":   fileStore,
+		}
+
+		for name, store := range stores {
+			Convey(name+" claims a delivery once", func() {
+				claimed, err := store.Claim("a", time.Minute)
+				So(err, ShouldBeNil)
+				So(claimed, ShouldBeTrue)
+
+				seen, err := store.Seen("a")
+				So(err, ShouldBeNil)
+				So(seen, ShouldBeTrue)
+
+				claimed, err = store.Claim("a", time.Minute)
+				So(err, ShouldBeNil)
+				So(claimed, ShouldBeFalse)
+			})
+
+			Convey(name+" allows claiming again after ttl", func() {
+				store.Claim("a", time.Minute)
+				current = current.Add(2 * time.Minute)
+
+				seen, _ := store.Seen("a")
+				So(seen, ShouldBeFalse)
+
+				claimed, err := store.Claim("a", time.Minute)
+				So(err, ShouldBeNil)
+				So(claimed, ShouldBeTrue)
+			})
+
+			Convey(name+" extends the ttl on complete", func() {
+				store.Claim("a", time.Minute)
+				So(store.Complete("a", time.Hour), ShouldBeNil)
+				current = current.Add(30 * time.Minute)
+
+				seen, _ := store.Seen("a")
+				So(seen, ShouldBeTrue)
+			})
+
+			Convey(name+" allows claiming again after release", func() {
+				store.Claim("a", time.Minute)
+				So(store.Release("a"), ShouldBeNil)
+
+				claimed, _ := store.Claim("a", time.Minute)
+				So(claimed, ShouldBeTrue)
+			})
+		}
+
+		Convey("MemoryDeliveryStore evicts least recently used deliveries", func() {
+			store := NewMemoryDeliveryStore(2)
+			store.Claim("a", time.Minute)
+			store.Claim("b", time.Minute)
+			store.Seen("a")
+			store.Claim("c", time.Minute)
+
+			seenA, _ := store.Seen("a")
+			seenB, _ := store.Seen("b")
+			So(seenA, ShouldBeTrue)
+			So(seenB, ShouldBeFalse)
+		})
+
+		Convey("FileDeliveryStore lets a single process take over an expired claim", func() {
+			fileStore.Claim("a", time.Minute)
+			current = current.Add(2 * time.Minute)
+
+			results := make(chan bool, 10)
+			for i := 0; i < cap(results); i++ {
+				go func() {
+					store, _ := NewFileDeliveryStore(dir)
+					claimed, _ := store.Claim("a", time.Minute)
+					results <- claimed
+				}()
+			}
+
+			claims := 0
+			for i := 0; i < cap(results); i++ {
+				if <-results {
+					claims++
+				}
+			}
+			So(claims, ShouldEqual, 1)
+
+			_, err := os.Stat(filepath.Join(dir, "a.json.lock"))
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+
+		Convey("FileDeliveryStore doesn't take over a claim another process is taking over", func() {
+			fileStore.Claim("a", time.Minute)
+			current = current.Add(2 * time.Minute)
+			So(ioutil.WriteFile(filepath.Join(dir, "a.json.lock"), []byte("{}"), 0644), ShouldBeNil)
+
+			claimed, err := fileStore.Claim("a", time.Minute)
+			So(err, ShouldBeNil)
+			So(claimed, ShouldBeFalse)
+
+			Convey("Unless its lock was left behind", func() {
+				stale := now().Add(-2 * staleClaimLockAge)
+				So(os.Chtimes(filepath.Join(dir, "a.json.lock"), stale, stale), ShouldBeNil)
+
+				claimed, err := fileStore.Claim("a", time.Minute)
+				So(err, ShouldBeNil)
+				So(claimed, ShouldBeTrue)
+			})
+		})
+
+		Convey("FileDeliveryStore lets a single process recover a stale lock", func() {
+			fileStore.Claim("a", time.Minute)
+			current = current.Add(2 * time.Minute)
+
+			lock := filepath.Join(dir, "a.json.lock")
+			So(ioutil.WriteFile(lock, []byte("{}"), 0644), ShouldBeNil)
+			stale := now().Add(-2 * staleClaimLockAge)
+			So(os.Chtimes(lock, stale, stale), ShouldBeNil)
+
+			results := make(chan bool, 10)
+			for i := 0; i < cap(results); i++ {
+				go func() {
+					store, _ := NewFileDeliveryStore(dir)
+					claimed, _ := store.Claim("a", time.Minute)
+					results <- claimed
+				}()
+			}
+
+			claims := 0
+			for i := 0; i < cap(results); i++ {
+				if <-results {
+					claims++
+				}
+			}
+			So(claims, ShouldEqual, 1)
+
+			locks, _ := filepath.Glob(lock + "*")
+			So(locks, ShouldBeEmpty)
+		})
+
+		Convey("removeStaleLock puts back a new lock that replaced the stale one", func() {
+			now = time.Now
+			lock := filepath.Join(dir, "a.json.lock")
+			So(ioutil.WriteFile(lock, []byte("new"), 0644), ShouldBeNil)
+
+			So(removeStaleLock(lock), ShouldBeTrue)
+
+			b, err := ioutil.ReadFile(lock)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "new")
+		})
+
+		Convey("FileDeliveryStore rejects unsafe ids", func() {
+			_, err := fileStore.Claim("../a", time.Minute)
+			So(err, ShouldEqual, ErrInvalidDeliveryID)
+		})
+	})
+}
+
+func TestDefaultHandlerDeduplication(t *testing.T) {
+	Convey("DefaultHandler with Deliveries", t, func() {
+		Deliveries = NewMemoryDeliveryStore(10)
+		Reset(func() {
+			ResetHandlers()
+			Deliveries = nil
+		})
+
+		calls := 0
+		var fnErr error
+		EventHandler(PullRequestEvent, func(e interface{}) (*Response, error) {
+			calls++
+			if fnErr != nil {
+				return nil, fnErr
+			}
+			return localSuccessResp("ok")
+		})
+
+		r := &events.APIGatewayProxyRequest{
+			Headers: map[string]string{
+				"X-GitHub-Event":    "pull_request",
+				"X-GitHub-Delivery": "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+			},
+			Body: PullRequestProxyRequest.Body,
+		}
+
+		Convey("It skips already processed deliveries", func() {
+			resp, err := DefaultHandler(r)
+			So(err, ShouldBeNil)
+			So(resp.Body, ShouldEqual, "ok")
+
+			resp, err = DefaultHandler(r)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 200)
+			So(resp.Body, ShouldEqual, "Dropping duplicate delivery: '72d3162e-cc78-11e3-81ab-4c9367dc0958'")
+			So(calls, ShouldEqual, 1)
+		})
+
+		Convey("It retries failed deliveries", func() {
+			fnErr = errors.New("failed")
+			resp, _ := DefaultHandler(r)
+			So(resp.StatusCode, ShouldEqual, 500)
+
+			fnErr = nil
+			resp, _ = DefaultHandler(r)
+			So(resp.Body, ShouldEqual, "ok")
+			So(calls, ShouldEqual, 2)
+		})
+
+		Convey("It records errors releasing failed deliveries", func() {
+			Deliveries = &failingReleaseStore{NewMemoryDeliveryStore(10)}
+			var buf bytes.Buffer
+			DeliveryLogger = NewJSONLogger(&buf)
+			Reset(func() { DeliveryLogger = nil })
+
+			fnErr = errors.New("failed")
+			resp, _ := DefaultHandler(r)
+			So(resp.StatusCode, ShouldEqual, 500)
+
+			var m map[string]interface{}
+			So(json.Unmarshal(buf.Bytes(), &m), ShouldBeNil)
+			So(m["error"], ShouldEqual, "failed; releasing delivery: release failed")
+		})
+
+		Convey("It doesn't deduplicate deliveries without id", func() {
+			DefaultHandler(PullRequestProxyRequest)
+			DefaultHandler(PullRequestProxyRequest)
+			So(calls, ShouldEqual, 2)
+		})
+	})
+}
+
+type failingReleaseStore struct {
+	DeliveryStore
+}
+
+func (s *failingReleaseStore) Release(id string) error {
+	return errors.New("release failed")
+}
@@ -0,0 +1,68 @@
+package ghhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/go-github/github"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDeliveryHandlers(t *testing.T) {
+	Convey("SNSHandler and EventBridgeHandler", t, func() {
+		Reset(func() { ResetHandlers() })
+
+		var actions []string
+		EventHandler(PullRequestEvent, func(e interface{}) (*Response, error) {
+			pr, ok := e.(*github.PullRequestEvent)
+			So(ok, ShouldBeTrue)
+
+			actions = append(actions, *pr.Action)
+			return localSuccessResp(*pr.Action)
+		})
+
+		delivery, err := json.Marshal(&Delivery{
+			Headers: map[string]string{"x-github-event": "pull_request"},
+			Body:    PullRequestProxyRequest.Body,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("It dispatches every SNS record", func() {
+			err := SNSHandler(&events.SNSEvent{Records: []events.SNSEventRecord{
+				{SNS: events.SNSEntity{MessageID: "1", Message: string(delivery)}},
+				{SNS: events.SNSEntity{MessageID: "2", Message: string(delivery)}},
+			}})
+			So(err, ShouldBeNil)
+			So(actions, ShouldResemble, []string{"opened", "opened"})
+		})
+
+		Convey("It returns error for undecodable SNS messages", func() {
+			err := SNSHandler(&events.SNSEvent{Records: []events.SNSEventRecord{
+				{SNS: events.SNSEntity{MessageID: "1", Message: "not json"}},
+			}})
+			So(err, ShouldNotBeNil)
+			So(actions, ShouldBeEmpty)
+		})
+
+		Convey("It dispatches EventBridge detail", func() {
+			err := EventBridgeHandler(&events.CloudWatchEvent{ID: "1", Detail: delivery})
+			So(err, ShouldBeNil)
+			So(actions, ShouldResemble, []string{"opened"})
+		})
+
+		Convey("It accepts payload as a JSON object", func() {
+			detail := fmt.Sprintf(`{"headers":{"X-GitHub-Event":"pull_request"},"payload":%s}`, PullRequestProxyRequest.Body)
+
+			err := EventBridgeHandler(&events.CloudWatchEvent{ID: "1", Detail: json.RawMessage(detail)})
+			So(err, ShouldBeNil)
+			So(actions, ShouldResemble, []string{"opened"})
+		})
+
+		Convey("It returns error when DefaultHandler fails", func() {
+			err := EventBridgeHandler(&events.CloudWatchEvent{ID: "1", Detail: json.RawMessage(`{"headers":{},"body":"{}"}`)})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,110 @@
+package ghhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// StatusCoder is implemented by errors that map to a response status code.
+// InputFn can return them to respond with a status other than 500, ie when a
+// webhook is deliberately rejected.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// ErrBadRequest is returned for malformed webhooks, ie missing headers or a
+// payload that can't be parsed.
+type ErrBadRequest struct {
+	Reason string
+}
+
+func (e *ErrBadRequest) Error() string {
+	return fmt.Sprintf("ERROR: bad request: %s", e.Reason)
+}
+
+// StatusCode returns the status code of the response for the error.
+func (e *ErrBadRequest) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// ErrUnauthorized is returned for webhooks that failed authentication, ie with
+// a bad signature.
+type ErrUnauthorized struct {
+	Reason string
+}
+
+func (e *ErrUnauthorized) Error() string {
+	return fmt.Sprintf("ERROR: unauthorized: %s", e.Reason)
+}
+
+// StatusCode returns the status code of the response for the error.
+func (e *ErrUnauthorized) StatusCode() int {
+	return http.StatusUnauthorized
+}
+
+// ErrForbidden is returned for webhooks that are authenticated but not allowed.
+type ErrForbidden struct {
+	Reason string
+}
+
+func (e *ErrForbidden) Error() string {
+	return fmt.Sprintf("ERROR: forbidden: %s", e.Reason)
+}
+
+// StatusCode returns the status code of the response for the error.
+func (e *ErrForbidden) StatusCode() int {
+	return http.StatusForbidden
+}
+
+// ErrUnprocessable is returned for well formed webhooks that can't be handled,
+// ie an unsupported event.
+type ErrUnprocessable struct {
+	Reason string
+}
+
+func (e *ErrUnprocessable) Error() string {
+	return fmt.Sprintf("ERROR: unprocessable: %s", e.Reason)
+}
+
+// StatusCode returns the status code of the response for the error.
+func (e *ErrUnprocessable) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+// errorBody is the JSON body rendered by DefaultErrorResponseFn.
+type errorBody struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+// StatusCodeOf returns the status code of the first error in err's chain that
+// implements StatusCoder, or 500 if there's none.
+func StatusCodeOf(err error) int {
+	var sc StatusCoder
+	if errors.As(err, &sc) {
+		return sc.StatusCode()
+	}
+
+	return http.StatusInternalServerError
+}
+
+// DefaultErrorResponseFn returns a response with the status code of the error
+// and a JSON body of the form '{"status":500,"error":"..."}'.
+func DefaultErrorResponseFn(err error) (*events.APIGatewayProxyResponse, error) {
+	statusCode := StatusCodeOf(err)
+
+	b, jsonErr := json.Marshal(&errorBody{Status: statusCode, Error: err.Error()})
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+
+	return &events.APIGatewayProxyResponse{
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(b),
+		StatusCode: statusCode,
+	}, nil
+}
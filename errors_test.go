@@ -0,0 +1,54 @@
+package ghhook
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDefaultErrorResponseFn(t *testing.T) {
+	Convey("DefaultErrorResponseFn", t, func() {
+		Convey("It responds with 500 for plain errors", func() {
+			resp, err := DefaultErrorResponseFn(errors.New("failed"))
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 500)
+			So(resp.Headers["Content-Type"], ShouldEqual, "application/json")
+			So(resp.Body, ShouldEqual, `{"status":500,"error":"failed"}`)
+		})
+
+		Convey("It honors the status code of the error", func() {
+			resp, err := DefaultErrorResponseFn(&ErrUnauthorized{Reason: "bad signature"})
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 401)
+			So(resp.Body, ShouldEqual, `{"status":401,"error":"ERROR: unauthorized: bad signature"}`)
+		})
+
+		Convey("It honors the status code of wrapped errors", func() {
+			resp, _ := DefaultErrorResponseFn(fmt.Errorf("handler: %w", &ErrUnprocessable{Reason: "unsupported"}))
+			So(resp.StatusCode, ShouldEqual, 422)
+		})
+	})
+
+	Convey("DefaultHandler", t, func() {
+		Reset(func() { ResetHandlers() })
+
+		Convey("It responds with 400 without event header", func() {
+			resp, err := DefaultHandler(&events.APIGatewayProxyRequest{})
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 400)
+		})
+
+		Convey("It responds with the status code returned by InputFn", func() {
+			EventHandler(PullRequestEvent, func(e interface{}) (*Response, error) {
+				return nil, &ErrForbidden{Reason: "not allowed"}
+			})
+
+			resp, err := DefaultHandler(PullRequestProxyRequest)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 403)
+		})
+	})
+}
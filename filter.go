@@ -0,0 +1,94 @@
+package ghhook
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// FilterMismatch describes which filter key rejected an event.
+type FilterMismatch struct {
+	Key string
+
+	// Value is the value at Key, or all of them if the path has wildcards.
+	Value   interface{}
+	Allowed []string
+
+	// Missing is true if the event has no value for Key.
+	Missing bool
+}
+
+func (m *FilterMismatch) String() string {
+	if m.Missing {
+		return fmt.Sprintf("No key:'%s' in event body", m.Key)
+	}
+
+	return fmt.Sprintf("Dropping unregistered value: '%v' for key:'%s', allowed: '%v'", m.Value, m.Key, m.Allowed)
+}
+
+// MatchFilters checks the event against the filters used by
+// EventHandlerActionFilter. Keys are field paths, ie 'pull_request.base.ref' or
+// 'pull_request.labels[*].name'; see ParsePath.
+//
+// All keys have to match, and a key matches if any of its values is equal to
+// any of the allowed values. Non string values are compared with their JSON
+// representation, ie 'true', '1' or 'null'.
+//
+// It returns nil if the event matches, otherwise the first mismatching key in
+// alphabetical order.
+func MatchFilters(filters map[string][]string, m map[string]interface{}) *FilterMismatch {
+	keys := make([]string, 0, len(filters))
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		allowedValues := filters[key]
+
+		values := ResolvePath(m, key)
+		if len(values) == 0 {
+			return &FilterMismatch{Key: key, Allowed: allowedValues, Missing: true}
+		}
+
+		if !matchAny(values, allowedValues) {
+			mismatch := &FilterMismatch{Key: key, Value: values, Allowed: allowedValues}
+			if len(values) == 1 {
+				mismatch.Value = values[0]
+			}
+
+			return mismatch
+		}
+	}
+
+	return nil
+}
+
+func matchAny(values []interface{}, allowedValues []string) bool {
+	for _, value := range values {
+		for _, allowed := range allowedValues {
+			if matchValue(value, allowed) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// matchValue compares a value decoded from JSON with a filter value.
+func matchValue(value interface{}, allowed string) bool {
+	switch v := value.(type) {
+	case string:
+		return v == allowed
+	case bool:
+		return strconv.FormatBool(v) == allowed
+	case float64:
+		f, err := strconv.ParseFloat(allowed, 64)
+		return err == nil && f == v
+	case nil:
+		return allowed == "null"
+	}
+
+	return false
+}
@@ -0,0 +1,134 @@
+package ghhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-github/github"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMatchFilters(t *testing.T) {
+	Convey("MatchFilters", t, func() {
+		m := map[string]interface{}{
+			"action": "opened",
+			"number": float64(1),
+			"merged": false,
+			"label":  nil,
+		}
+
+		Convey("It matches when all keys match", func() {
+			So(MatchFilters(map[string][]string{
+				"action": []string{"closed", "opened"},
+				"number": []string{"1"},
+				"merged": []string{"false"},
+				"label":  []string{"null"},
+			}, m), ShouldBeNil)
+		})
+
+		Convey("It rejects when any key doesn't match", func() {
+			for i := 0; i < 10; i++ {
+				mismatch := MatchFilters(map[string][]string{
+					"action": []string{"opened"},
+					"number": []string{"2"},
+				}, m)
+
+				So(mismatch, ShouldResemble, &FilterMismatch{Key: "number", Value: float64(1), Allowed: []string{"2"}})
+			}
+		})
+
+		Convey("It rejects missing keys", func() {
+			mismatch := MatchFilters(map[string][]string{"ref": []string{"master"}}, m)
+			So(mismatch.Missing, ShouldBeTrue)
+			So(mismatch.String(), ShouldEqual, "No key:'ref' in event body")
+		})
+
+		Convey("It doesn't match values of other types", func() {
+			So(MatchFilters(map[string][]string{"merged": []string{"0"}}, m), ShouldNotBeNil)
+			So(MatchFilters(map[string][]string{"number": []string{"true"}}, m), ShouldNotBeNil)
+		})
+	})
+
+	Convey("EventHandlerActionFilter", t, func() {
+		Reset(func() { ResetHandlers() })
+
+		var fn InputFn = func(e interface{}) (*Response, error) {
+			pr, _ := e.(*github.PullRequestEvent)
+			return localSuccessResp(fmt.Sprintf("%s", *pr.Action))
+		}
+
+		Convey("It checks every key", func() {
+			EventHandlerActionFilter(PullRequestEvent, map[string][]string{
+				"action": []string{"opened"},
+				"number": []string{"2"},
+			}, fn)
+
+			resp, err := DefaultHandler(PullRequestProxyRequest)
+			So(err, ShouldBeNil)
+			So(resp.Body, ShouldEqual, "Dropping unregistered value: '1' for key:'number', allowed: '[2]'")
+		})
+
+		Convey("It calls fn when every key matches", func() {
+			EventHandlerActionFilter(PullRequestEvent, map[string][]string{
+				"action": []string{"opened"},
+				"number": []string{"1"},
+			}, fn)
+
+			resp, err := DefaultHandler(PullRequestProxyRequest)
+			So(err, ShouldBeNil)
+			So(resp.Body, ShouldEqual, "opened")
+		})
+	})
+}
+
+func TestResolvePath(t *testing.T) {
+	Convey("ResolvePath", t, func() {
+		var m map[string]interface{}
+		So(json.Unmarshal([]byte(`{
+			"action": "labeled",
+			"pull_request": {
+				"base": {"ref": "master"},
+				"labels": [{"name": "bug"}, {"name": "needs-qa"}]
+			},
+			"sender": {"type": "User"}
+		}`), &m), ShouldBeNil)
+
+		Convey("It resolves nested keys", func() {
+			So(ResolvePath(m, "pull_request.base.ref"), ShouldResemble, []interface{}{"master"})
+		})
+
+		Convey("It resolves array indexes", func() {
+			So(ResolvePath(m, "pull_request.labels[0].name"), ShouldResemble, []interface{}{"bug"})
+			So(ResolvePath(m, "pull_request.labels[-1].name"), ShouldResemble, []interface{}{"needs-qa"})
+			So(ResolvePath(m, "pull_request.labels[2].name"), ShouldBeEmpty)
+		})
+
+		Convey("It resolves wildcards", func() {
+			So(ResolvePath(m, "pull_request.labels[*].name"), ShouldResemble, []interface{}{"bug", "needs-qa"})
+			So(ResolvePath(m, "pull_request.base.*"), ShouldResemble, []interface{}{"master"})
+		})
+
+		Convey("It resolves nothing for missing keys", func() {
+			So(ResolvePath(m, "pull_request.head.ref"), ShouldBeEmpty)
+			So(ResolvePath(m, "action.name"), ShouldBeEmpty)
+		})
+
+		Convey("It rejects invalid paths", func() {
+			for _, path := range []string{"", "a..b", "[0]", "a[", "a[x]", "a]b", "a[0]b"} {
+				So(ParsePath(path), ShouldNotBeNil)
+			}
+		})
+
+		Convey("MatchFilters uses paths", func() {
+			So(MatchFilters(map[string][]string{
+				"pull_request.base.ref":       []string{"master"},
+				"pull_request.labels[*].name": []string{"needs-qa"},
+				"sender.type":                 []string{"User"},
+			}, m), ShouldBeNil)
+
+			mismatch := MatchFilters(map[string][]string{"pull_request.labels[*].name": []string{"security"}}, m)
+			So(mismatch.Value, ShouldResemble, []interface{}{"bug", "needs-qa"})
+		})
+	})
+}
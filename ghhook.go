@@ -1,8 +1,6 @@
 package ghhook
 
 import (
-	"encoding/json"
-	"errors"
 	"fmt"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -38,7 +36,7 @@ var (
 	// required header.
 	//
 	// Event names are sent in the header under 'X-GitHub-Event' by Github.
-	ErrNoGithubEventHeader = errors.New("ERROR: no 'X-GitHub-Event' header")
+	ErrNoGithubEventHeader error = &ErrBadRequest{Reason: "no 'X-GitHub-Event' header"}
 )
 
 // EventHandler appends the given InputFn to the given event.
@@ -53,11 +51,15 @@ var (
 //		}, nil
 //	})
 func EventHandler(event Event, fn InputFn) {
-	Handlers[event] = append(Handlers[event], fn)
+	Handlers[event] = append(Handlers[event], checkSenders(fn, false))
 }
 
 // EventHandlerActionFilter is similar to EventHandler with addition of checking
-// if the top level keys match the given filters.
+// if the event matches the given filters. Keys are field paths, ie 'action' or
+// 'pull_request.base.ref', and all have to match, each to any of its values;
+// see MatchFilters.
+//
+// It panics if any of the keys is not a valid path.
 //
 // Example:
 //  # only listens to 'opened' action.
@@ -67,34 +69,37 @@ func EventHandler(event Event, fn InputFn) {
 //	  func(e interface{}) (*ghhook.Response, error) { return nil, nil },
 //	)
 func EventHandlerActionFilter(event Event, filters map[string][]string, fn InputFn) {
-	wrappedFn := func(i interface{}) (*Response, error) {
-		b, err := json.Marshal(i)
-		if err != nil {
-			return nil, err
-		}
-
-		var m map[string]interface{}
-		if err := json.Unmarshal(b, &m); err != nil {
-			return nil, err
+	for key := range filters {
+		if err := ParsePath(key); err != nil {
+			panic(err)
 		}
+	}
 
-		for key, allowedValues := range filters {
-			value, ok := m[key]
-			if !ok {
-				return localSuccessResp(fmt.Sprintf("No key:'%s' in event body", key))
+	wrappedFn := func(i interface{}) (*Response, error) {
+		reason, err := traceFilter(i, "action_filter", func() (string, error) {
+			m, err := eventMap(i)
+			if err != nil {
+				return "", err
 			}
 
-			for _, allowed := range allowedValues {
-				if value == allowed {
-					return fn(i)
-				}
+			if mismatch := MatchFilters(filters, m); mismatch != nil {
+				return mismatch.String(), nil
 			}
+
+			return "", nil
+		})
+		if err != nil {
+			return nil, err
 		}
 
-		return localSuccessResp(fmt.Sprintf("Dropping unregistered value: '%v'", filters))
+		if reason != "" {
+			return dropEvent(i, reason)
+		}
+
+		return fn(i)
 	}
 
-	Handlers[event] = append(Handlers[event], wrappedFn)
+	Handlers[event] = append(Handlers[event], checkSenders(wrappedFn, false))
 }
 
 // EventHandlerFunctionFilter is similar to EventHandler with addition of
@@ -109,24 +114,30 @@ func EventHandlerActionFilter(event Event, filters map[string][]string, fn Input
 //	)
 func EventHandlerFunctionFilter(event Event, filterFn func(map[string]interface{}) bool, fn InputFn) {
 	wrappedFn := func(i interface{}) (*Response, error) {
-		b, err := json.Marshal(i)
-		if err != nil {
-			return nil, err
-		}
+		reason, err := traceFilter(i, "function_filter", func() (string, error) {
+			m, err := eventMap(i)
+			if err != nil {
+				return "", err
+			}
 
-		var m map[string]interface{}
-		if err := json.Unmarshal(b, &m); err != nil {
+			if !filterFn(m) {
+				return "Dropping unmatched event for function", nil
+			}
+
+			return "", nil
+		})
+		if err != nil {
 			return nil, err
 		}
 
-		if !filterFn(m) {
-			return localSuccessResp("Dropping unmatched event for function")
+		if reason != "" {
+			return dropEvent(i, reason)
 		}
 
 		return fn(i)
 	}
 
-	Handlers[event] = append(Handlers[event], wrappedFn)
+	Handlers[event] = append(Handlers[event], checkSenders(wrappedFn, false))
 }
 
 // DefaultHandler is a Lambda compatible handler that receives
@@ -137,37 +148,165 @@ func EventHandlerFunctionFilter(event Event, filterFn func(map[string]interface{
 // response is returned, but if any of them fails, it stops execution and
 // returns the error.
 func DefaultHandler(r *events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
-	eventName, ok := r.Headers["X-GitHub-Event"]
+	rec := newDeliveryRecord(r)
+
+	resp, err := handleDelivery(r, rec)
+	rec.finish(resp)
+
+	return resp, err
+}
+
+// handleDelivery dispatches the request. Replayed deliveries were already
+// checked and archived when they were received, so they skip
+// SourceIPAllowlist, ReplayProtection, Deliveries and DeliveryArchive.
+func handleDelivery(r *events.APIGatewayProxyRequest, rec *DeliveryRecord) (*events.APIGatewayProxyResponse, error) {
+	if SourceIPAllowlist != nil && !rec.Replay {
+		if err := SourceIPAllowlist.Check(r); err != nil {
+			return rec.fail(OutcomeRejected, err)
+		}
+	}
+
+	if err := verifyDelivery(rec, r); err != nil {
+		return rec.fail(OutcomeRejected, err)
+	}
+
+	eventName, ok := headerValue(r.Headers, "X-GitHub-Event")
 	if !ok {
-		return ErrorResponseFn(ErrNoGithubEventHeader)
+		return rec.fail(OutcomeRejected, ErrNoGithubEventHeader)
 	}
 
 	fns, ok := Handlers[Event(eventName)]
 	if !ok {
+		if err := archiveDelivery(rec, eventName); err != nil {
+			return rec.fail(OutcomeFailed, err)
+		}
+
+		rec.Outcome = OutcomeDroppedUnregistered
 		return SuccessResponseFn(fmt.Sprintf("Dropping unregistered event: '%s'", eventName))
 	}
 
-	i, err := github.ParseWebHook(eventName, []byte(r.Body))
-	if err != nil {
-		return ErrorResponseFn(err)
+	deliveryID := rec.DeliveryID
+	if rec.Replay {
+		deliveryID = ""
+	}
+
+	// a failed delivery is released from both stores so Github can redeliver
+	// it.
+	var guarded, claimed bool
+	fail := func(err error) (*events.APIGatewayProxyResponse, error) {
+		resp, err := rec.fail(OutcomeFailed, err)
+
+		if guarded {
+			rec.recordReleaseError(ReplayProtection.Release(deliveryID))
+		}
+		if claimed {
+			rec.recordReleaseError(Deliveries.Release(deliveryID))
+		}
+
+		return resp, err
 	}
 
-	var lastResponse *Response
-	for _, fn := range fns {
+	if ReplayProtection != nil && !rec.Replay {
+		if err := ReplayProtection.Check(deliveryID, Event(eventName), r.Body); err != nil {
+			return rec.fail(OutcomeRejected, err)
+		}
+		guarded = true
+	}
+
+	if Deliveries != nil && deliveryID != "" {
 		var err error
-		if lastResponse, err = fn(i); err != nil {
-			return ErrorResponseFn(err)
+		claimed, err = Deliveries.Claim(deliveryID, DeliveryClaimTTL)
+		if err != nil {
+			return fail(err)
+		}
+
+		if !claimed {
+			rec.Outcome = OutcomeDuplicate
+			return SuccessResponseFn(fmt.Sprintf("Dropping duplicate delivery: '%s'", deliveryID))
+		}
+	}
+
+	// deliveries are archived once they passed ReplayProtection and
+	// Deliveries, so replays and duplicates are not archived again.
+	if err := archiveDelivery(rec, eventName); err != nil {
+		return fail(err)
+	}
+
+	lastResponse, err := runHandlers(rec, eventName, fns)
+	if err != nil {
+		return fail(err)
+	}
+
+	if claimed {
+		if err := Deliveries.Complete(deliveryID, DeliveryTTL); err != nil {
+			return fail(err)
+		}
+	}
+
+	rec.Outcome = OutcomeDroppedFiltered
+	for _, result := range rec.Handlers {
+		if result.Outcome == HandlerSucceeded {
+			rec.Outcome = OutcomeSucceeded
 		}
 	}
 
 	return convertResponseToEventsResponse(lastResponse), nil
 }
 
-func DefaultErrorResponseFn(err error) (*events.APIGatewayProxyResponse, error) {
-	return &events.APIGatewayProxyResponse{
-		Body:       err.Error(),
-		StatusCode: 500,
-	}, nil
+// runHandlers parses the body and runs fns in order, stopping at the first
+// error. Filters of fns share the generic map decoded from the body.
+func runHandlers(rec *DeliveryRecord, eventName string, fns []InputFn) (*Response, error) {
+	p := rec.payload
+
+	_, span := startSpan(rec.ctx, "ghhook.parse")
+	i, err := github.ParseWebHook(eventName, p.body)
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return nil, &ErrBadRequest{Reason: err.Error()}
+	}
+	span.End()
+
+	return withPayload(i, p, func() (*Response, error) {
+		var lastResponse *Response
+		for index, fn := range fns {
+			start := now()
+			result := HandlerResult{Index: index, Outcome: HandlerSucceeded}
+			p.dropReason = ""
+
+			var span Span
+			p.ctx, span = startSpan(rec.ctx, "ghhook.handler")
+			span.SetAttribute("ghhook.handler.index", index)
+
+			lastResponse, err = fn(i)
+
+			result.Duration = now().Sub(start)
+			if err != nil {
+				result.Outcome = HandlerFailed
+				result.Error = err.Error()
+			} else if p.dropReason != "" {
+				result.Outcome = HandlerDropped
+				result.Reason = p.dropReason
+			}
+			if err == nil {
+				result.Response = lastResponse
+			}
+
+			if err != nil {
+				span.RecordError(err)
+			}
+			span.SetAttribute("ghhook.handler.outcome", string(result.Outcome))
+			span.End()
+			p.ctx = rec.ctx
+
+			rec.Handlers = append(rec.Handlers, result)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return lastResponse, nil
+	})
 }
 
 func DefaultSuccessResponseFn(body string) (*events.APIGatewayProxyResponse, error) {
@@ -184,6 +323,16 @@ func localSuccessResp(body string) (*Response, error) {
 	}, nil
 }
 
+// dropEvent returns the success response for an event dropped by the filters
+// of an InputFn, and records the reason for DeliveryRecord.
+func dropEvent(i interface{}, reason string) (*Response, error) {
+	if p, ok := payloads.Load(i); ok {
+		p.(*payload).dropReason = reason
+	}
+
+	return localSuccessResp(reason)
+}
+
 // ResetHandlers is used to clear out the handlers. This is mainly to be used in tests.
 func ResetHandlers() {
 	Handlers = map[Event][]InputFn{}
@@ -10,18 +10,23 @@ import (
 
 func TestEventHandler(t *testing.T) {
 	Convey("EventHandler", t, func() {
+		calls := 0
 		var fn InputFn = func(e interface{}) (*Response, error) {
+			calls++
 			return nil, nil
 		}
 
 		Reset(func() { ResetHandlers() })
 
+		// the stored InputFn wraps fn with the IgnoredSenders check.
 		Convey("It saves input fn to event name", func() {
 			EventHandler(PullRequestEvent, fn)
 
 			So(len(Handlers), ShouldEqual, 1)
 			So(len(Handlers[PullRequestEvent]), ShouldEqual, 1)
-			So(Handlers[PullRequestEvent][0], ShouldEqual, fn)
+
+			Handlers[PullRequestEvent][0](&github.PullRequestEvent{})
+			So(calls, ShouldEqual, 1)
 		})
 
 		Convey("It appends input fn to event name", func() {
@@ -29,8 +34,10 @@ func TestEventHandler(t *testing.T) {
 			EventHandler(PullRequestEvent, fn)
 
 			So(len(Handlers[PullRequestEvent]), ShouldEqual, 2)
-			So(Handlers[PullRequestEvent][0], ShouldEqual, fn)
-			So(Handlers[PullRequestEvent][1], ShouldEqual, fn)
+
+			Handlers[PullRequestEvent][0](&github.PullRequestEvent{})
+			Handlers[PullRequestEvent][1](&github.PullRequestEvent{})
+			So(calls, ShouldEqual, 2)
 		})
 	})
 }
@@ -63,7 +70,7 @@ func TestDefaultHandler(t *testing.T) {
 
 			resp, err := DefaultHandler(PullRequestProxyRequest)
 			So(err, ShouldBeNil)
-			So(resp.Body, ShouldEqual, "Dropping unregistered value: 'map[action:[reopened]]'")
+			So(resp.Body, ShouldEqual, "Dropping unregistered value: 'opened' for key:'action', allowed: '[reopened]'")
 		})
 
 		Convey("It returns error if action filter is used with event with no action", func() {
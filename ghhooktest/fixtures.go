@@ -0,0 +1,168 @@
+// Package ghhooktest provides fixture payloads of every Github webhook event
+// and builders of signed requests, to test ghhook handlers.
+//
+// Example:
+//
+//	payload := ghhooktest.Fixture(ghhook.PullRequestEvent).
+//		WithAction(ghhook.PullRequestClosed).
+//		WithRepo("WalkerAndCoBrandsInc/api")
+//
+//	resp, err := ghhook.DefaultHandler(ghhooktest.NewProxyRequest(ghhook.PullRequestEvent, payload))
+package ghhooktest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/WalkerAndCoBrandsInc/ghhook"
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// Payload is the decoded body of a webhook. Its methods modify the payload in
+// place and return it, so they can be chained.
+type Payload map[string]interface{}
+
+// Fixture returns a new copy of the fixture payload of the event, so it can be
+// modified freely. It panics if there's no fixture for the event; see Events.
+//
+// Fixtures of events with actions have their most common action, ie 'opened'
+// for pull requests; use WithAction to change it.
+func Fixture(event ghhook.Event) Payload {
+	b, err := fixtures.ReadFile("fixtures/" + string(event) + ".json")
+	if err != nil {
+		panic(fmt.Sprintf("ERROR: no fixture for event '%s'", event))
+	}
+
+	var p Payload
+	if err := json.Unmarshal(b, &p); err != nil {
+		panic(fmt.Sprintf("ERROR: decoding fixture of event '%s': %v", event, err))
+	}
+
+	return p
+}
+
+// Events returns the events that have a fixture, sorted by name.
+func Events() []ghhook.Event {
+	entries, _ := fixtures.ReadDir("fixtures")
+
+	events := make([]ghhook.Event, 0, len(entries))
+	for _, entry := range entries {
+		events = append(events, ghhook.Event(strings.TrimSuffix(entry.Name(), ".json")))
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i] < events[j] })
+
+	return events
+}
+
+// Set sets the value at the dotted path, ie 'pull_request.base.ref', creating
+// the intermediate objects that don't exist.
+func (p Payload) Set(path string, value interface{}) Payload {
+	keys := strings.Split(path, ".")
+
+	m := map[string]interface{}(p)
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[key] = next
+		}
+		m = next
+	}
+
+	m[keys[len(keys)-1]] = value
+
+	return p
+}
+
+// Get returns the value at the dotted path, or nil if it doesn't exist.
+func (p Payload) Get(path string) interface{} {
+	var v interface{} = map[string]interface{}(p)
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+
+	return v
+}
+
+// WithAction sets the action of the payload.
+func (p Payload) WithAction(action ghhook.Action) Payload {
+	return p.Set("action", string(action))
+}
+
+// WithRepo sets the full name, ie 'owner/name', of the repository of the
+// payload, and the fields derived from it.
+func (p Payload) WithRepo(fullName string) Payload {
+	parts := strings.SplitN(fullName, "/", 2)
+	if len(parts) != 2 {
+		panic(fmt.Sprintf("ERROR: invalid repository '%s', expected 'owner/name'", fullName))
+	}
+
+	p.Set("repository.full_name", fullName)
+	p.Set("repository.name", parts[1])
+	p.Set("repository.html_url", "https://github.com/"+fullName)
+	p.Set("repository.url", "https://api.github.com/repos/"+fullName)
+
+	// the owner of push events only has a name and an email.
+	if _, ok := p.Get("repository.owner.login").(string); ok {
+		p.Set("repository.owner.login", parts[0])
+	} else {
+		p.Set("repository.owner.name", parts[0])
+	}
+
+	return p
+}
+
+// WithSender sets the login of the sender of the payload, as a user.
+func (p Payload) WithSender(login string) Payload {
+	p.Set("sender.login", login)
+	p.Set("sender.type", "User")
+	p.Set("sender.html_url", "https://github.com/"+login)
+
+	return p
+}
+
+// WithBotSender sets the sender of the payload to the bot of a Github App,
+// ie 'dependabot[bot]'.
+func (p Payload) WithBotSender(login string) Payload {
+	p.WithSender(login)
+	p.Set("sender.type", "Bot")
+
+	return p
+}
+
+// WithLabel sets the label of 'labeled' and 'unlabeled' actions, and adds it to
+// the labels of the issue or pull request of the payload.
+func (p Payload) WithLabel(name string) Payload {
+	label := map[string]interface{}{"name": name, "color": "ededed", "default": false}
+	p.Set("label", label)
+
+	for _, key := range []string{"pull_request", "issue"} {
+		if _, ok := p[key].(map[string]interface{}); !ok {
+			continue
+		}
+
+		labels, _ := p.Get(key + ".labels").([]interface{})
+		p.Set(key+".labels", append(labels, label))
+	}
+
+	return p
+}
+
+// JSON returns the encoded payload.
+func (p Payload) JSON() string {
+	b, err := json.Marshal(p)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR: encoding payload: %v", err))
+	}
+
+	return string(b)
+}
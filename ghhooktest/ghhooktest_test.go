@@ -0,0 +1,156 @@
+package ghhooktest
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WalkerAndCoBrandsInc/ghhook"
+	"github.com/google/go-github/github"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFixtures(t *testing.T) {
+	Convey("Fixture", t, func() {
+		Convey("It has a fixture for every event", func() {
+			So(Events(), ShouldResemble, []ghhook.Event{
+				ghhook.CommitCommentEvent, ghhook.CreateEvent, ghhook.DeleteEvent, ghhook.DeploymentEvent,
+				ghhook.DeploymentStatusEvent, ghhook.ForkEvent, ghhook.GollumEvent, ghhook.InstallationEvent,
+				ghhook.IntegrationInstallationEvent, ghhook.IssueCommentEvent, ghhook.IssuesEvent, ghhook.LabelEvent,
+				ghhook.MemberEvent, ghhook.MembershipEvent, ghhook.MilestoneEvent, ghhook.OrgBlockEvent,
+				ghhook.OrganizationEvent, ghhook.PageBuildEvent, ghhook.PingEvent, ghhook.ProjectEvent,
+				ghhook.ProjectCardEvent, ghhook.ProjectColumnEvent, ghhook.PublicEvent, ghhook.PullRequestEvent,
+				ghhook.PullRequestReviewEvent, ghhook.PullRequestReviewCommentEvent, ghhook.PushEvent,
+				ghhook.ReleaseEvent, ghhook.RepositoryEvent, ghhook.StatusEvent, ghhook.TeamEvent,
+				ghhook.TeamAddEvent, ghhook.WatchEvent,
+			})
+		})
+
+		Convey("Fixtures are parsed by go-github", func() {
+			for _, event := range Events() {
+				// go-github doesn't parse the deprecated integration_installation.
+				if event == ghhook.IntegrationInstallationEvent {
+					continue
+				}
+
+				_, err := github.ParseWebHook(string(event), []byte(Fixture(event).JSON()))
+				So(err, ShouldBeNil)
+			}
+		})
+
+		Convey("Fixtures with actions have a valid action", func() {
+			for event := range ghhook.EventActions {
+				action, _ := Fixture(event).Get("action").(string)
+				So(ghhook.ValidAction(event, ghhook.Action(action)), ShouldBeTrue)
+			}
+		})
+
+		Convey("It returns a new copy", func() {
+			Fixture(ghhook.PushEvent).Set("ref", "refs/heads/other")
+
+			So(Fixture(ghhook.PushEvent).Get("ref"), ShouldEqual, "refs/heads/changes")
+		})
+
+		Convey("It panics for events without fixture", func() {
+			So(func() { Fixture("check_run") }, ShouldPanic)
+		})
+	})
+
+	Convey("Payload", t, func() {
+		Convey("It sets nested values", func() {
+			p := Payload{}.Set("pull_request.base.ref", "master")
+
+			So(p.Get("pull_request.base.ref"), ShouldEqual, "master")
+			So(p.Get("pull_request.head.ref"), ShouldBeNil)
+		})
+
+		Convey("It sets the action, repository and sender", func() {
+			p := Fixture(ghhook.PullRequestEvent).
+				WithAction(ghhook.PullRequestClosed).
+				WithRepo("WalkerAndCoBrandsInc/api").
+				WithBotSender("dependabot[bot]")
+
+			So(p.Get("action"), ShouldEqual, "closed")
+			So(p.Get("repository.full_name"), ShouldEqual, "WalkerAndCoBrandsInc/api")
+			So(p.Get("repository.name"), ShouldEqual, "api")
+			So(p.Get("repository.owner.login"), ShouldEqual, "WalkerAndCoBrandsInc")
+			So(p.Get("sender.login"), ShouldEqual, "dependabot[bot]")
+			So(p.Get("sender.type"), ShouldEqual, "Bot")
+		})
+
+		Convey("It sets the repository owner of push events", func() {
+			p := Fixture(ghhook.PushEvent).WithRepo("WalkerAndCoBrandsInc/api")
+
+			So(p.Get("repository.owner.name"), ShouldEqual, "WalkerAndCoBrandsInc")
+			So(p.Get("repository.owner.login"), ShouldBeNil)
+		})
+
+		Convey("It adds labels", func() {
+			p := Fixture(ghhook.IssuesEvent).WithAction(ghhook.IssuesLabeled).WithLabel("security")
+
+			So(p.Get("label.name"), ShouldEqual, "security")
+			So(len(p.Get("issue.labels").([]interface{})), ShouldEqual, 2)
+		})
+	})
+}
+
+func TestRequests(t *testing.T) {
+	Convey("NewProxyRequest", t, func() {
+		var got interface{}
+		ghhook.EventHandlerWithOptions(ghhook.PullRequestEvent, func(e interface{}) (*ghhook.Response, error) {
+			got = e
+			return &ghhook.Response{StatusCode: 200, Body: "ok"}, nil
+		}, ghhook.InRepos("WalkerAndCoBrandsInc/*"))
+
+		ghhook.WebhookSecret = "secret"
+		Reset(func() {
+			ghhook.WebhookSecret = ""
+			ghhook.ResetHandlers()
+		})
+
+		payload := Fixture(ghhook.PullRequestEvent).WithRepo("WalkerAndCoBrandsInc/api")
+
+		Convey("It builds signed requests for DefaultHandler", func() {
+			req := NewProxyRequest(ghhook.PullRequestEvent, payload, WithSecret("secret"), WithDeliveryID("d1"), WithSourceIP("192.30.252.1"))
+
+			So(req.Headers["X-GitHub-Delivery"], ShouldEqual, "d1")
+			So(req.RequestContext.Identity.SourceIP, ShouldEqual, "192.30.252.1")
+
+			resp, err := ghhook.DefaultHandler(req)
+			So(err, ShouldBeNil)
+			So(resp.Body, ShouldEqual, "ok")
+			So(got.(*github.PullRequestEvent).GetRepo().GetFullName(), ShouldEqual, "WalkerAndCoBrandsInc/api")
+		})
+
+		Convey("It generates delivery ids", func() {
+			a := NewProxyRequest(ghhook.PingEvent, "{}")
+			b := NewProxyRequest(ghhook.PingEvent, "{}")
+
+			So(a.Headers["X-GitHub-Delivery"], ShouldHaveLength, 36)
+			So(a.Headers["X-GitHub-Delivery"], ShouldNotEqual, b.Headers["X-GitHub-Delivery"])
+		})
+
+		Convey("It overrides and removes headers", func() {
+			req := NewProxyRequest(ghhook.PingEvent, map[string]string{"zen": "ok"}, WithHeader("X-GitHub-Event", ""), WithHeader("X-Custom", "1"))
+
+			So(req.Headers, ShouldNotContainKey, "X-GitHub-Event")
+			So(req.Headers["X-Custom"], ShouldEqual, "1")
+			So(req.Body, ShouldEqual, `{"zen":"ok"}`)
+		})
+
+		Convey("It builds http requests for HTTPHandler", func() {
+			w := httptest.NewRecorder()
+			ghhook.HTTPHandler().ServeHTTP(w, NewHTTPRequest(ghhook.PullRequestEvent, payload, WithSecret("secret")))
+
+			body, _ := ioutil.ReadAll(w.Body)
+			So(w.Code, ShouldEqual, 200)
+			So(string(body), ShouldEqual, "ok")
+		})
+
+		Convey("Unsigned requests are rejected", func() {
+			resp, _ := ghhook.DefaultHandler(NewProxyRequest(ghhook.PullRequestEvent, payload))
+
+			So(resp.StatusCode, ShouldEqual, 401)
+		})
+	})
+}
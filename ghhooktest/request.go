@@ -0,0 +1,151 @@
+package ghhooktest
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/WalkerAndCoBrandsInc/ghhook"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// RequestOption customizes the requests built by NewProxyRequest and
+// NewHTTPRequest.
+type RequestOption func(*request)
+
+type request struct {
+	deliveryID string
+	secret     string
+	sourceIP   string
+	headers    map[string]string
+}
+
+// WithSecret signs the request with the secret, in both the
+// 'X-Hub-Signature-256' and 'X-Hub-Signature' headers.
+func WithSecret(secret string) RequestOption {
+	return func(r *request) {
+		r.secret = secret
+	}
+}
+
+// WithDeliveryID sets the 'X-GitHub-Delivery' header, a random UUID by
+// default.
+func WithDeliveryID(id string) RequestOption {
+	return func(r *request) {
+		r.deliveryID = id
+	}
+}
+
+// WithSourceIP sets the source ip of the request, ie to test
+// ghhook.SourceIPAllowlist.
+func WithSourceIP(ip string) RequestOption {
+	return func(r *request) {
+		r.sourceIP = ip
+	}
+}
+
+// WithHeader sets a header of the request. Headers set this way take
+// precedence over the ones set by the builder, and an empty value removes the
+// header.
+func WithHeader(key, value string) RequestOption {
+	return func(r *request) {
+		r.headers[key] = value
+	}
+}
+
+// NewProxyRequest returns the APIGatewayProxyRequest Github would send for the
+// event. The body is a Payload, a string, a []byte or any value encoded to
+// JSON.
+func NewProxyRequest(event ghhook.Event, body interface{}, opts ...RequestOption) *events.APIGatewayProxyRequest {
+	r := &request{headers: map[string]string{}}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.deliveryID == "" {
+		r.deliveryID = NewDeliveryID()
+	}
+
+	b := encodeBody(body)
+
+	headers := map[string]string{
+		"Content-Type":      "application/json",
+		"User-Agent":        "GitHub-Hookshot/ghhooktest",
+		"X-GitHub-Event":    string(event),
+		"X-GitHub-Delivery": r.deliveryID,
+	}
+
+	if r.secret != "" {
+		headers["X-Hub-Signature"] = ghhook.SignSHA1(r.secret, b)
+		headers["X-Hub-Signature-256"] = ghhook.SignSHA256(r.secret, b)
+	}
+
+	for k, v := range r.headers {
+		if v == "" {
+			delete(headers, k)
+			continue
+		}
+		headers[k] = v
+	}
+
+	req := &events.APIGatewayProxyRequest{
+		Path:       "/",
+		HTTPMethod: http.MethodPost,
+		Headers:    headers,
+		Body:       b,
+	}
+	req.RequestContext.Identity.SourceIP = r.sourceIP
+
+	return req
+}
+
+// NewHTTPRequest returns the http request Github would send for the event,
+// ie to test ghhook.HTTPHandler with httptest.
+func NewHTTPRequest(event ghhook.Event, body interface{}, opts ...RequestOption) *http.Request {
+	proxyReq := NewProxyRequest(event, body, opts...)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(proxyReq.Body))
+	for k, v := range proxyReq.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if ip := proxyReq.RequestContext.Identity.SourceIP; ip != "" {
+		req.RemoteAddr = ip + ":443"
+	}
+
+	return req
+}
+
+// NewDeliveryID returns a random UUID, like the 'X-GitHub-Delivery' header.
+func NewDeliveryID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("ERROR: generating delivery id: %v", err))
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func encodeBody(body interface{}) string {
+	switch b := body.(type) {
+	case string:
+		return b
+	case []byte:
+		return string(b)
+	case Payload:
+		return b.JSON()
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR: encoding body: %v", err))
+	}
+
+	return string(b)
+}
@@ -0,0 +1,107 @@
+package ghhook
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// glob is a compiled glob pattern. '*' matches any characters except '/',
+// '**' matches any characters including '/', '?' matches a single character
+// except '/' and '[...]' matches a character class.
+type glob struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func compileGlob(pattern string, caseInsensitive bool) (*glob, error) {
+	var b strings.Builder
+	if caseInsensitive {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				// '**/' also matches no directories at all.
+				b.WriteString("(?:.*/)?")
+				i += 2
+			} else if strings.HasPrefix(pattern[i:], "**") {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("ERROR: invalid glob '%s'", pattern)
+			}
+
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			b.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: invalid glob '%s': %v", pattern, err)
+	}
+
+	return &glob{pattern: pattern, re: re}, nil
+}
+
+func (g *glob) Match(name string) bool {
+	return g.re.MatchString(name)
+}
+
+// globs is a list of globs that matches if any of them match.
+type globs []*glob
+
+// mustCompileGlobs compiles the patterns and panics if any of them is invalid,
+// so invalid patterns are reported when handlers are registered.
+func mustCompileGlobs(patterns []string, caseInsensitive bool) globs {
+	gs := make(globs, len(patterns))
+	for i, pattern := range patterns {
+		g, err := compileGlob(pattern, caseInsensitive)
+		if err != nil {
+			panic(err)
+		}
+
+		gs[i] = g
+	}
+
+	return gs
+}
+
+func (gs globs) Match(name string) bool {
+	for _, g := range gs {
+		if g.Match(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (gs globs) String() string {
+	patterns := make([]string, len(gs))
+	for i, g := range gs {
+		patterns[i] = g.pattern
+	}
+
+	return fmt.Sprintf("%v", patterns)
+}
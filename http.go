@@ -0,0 +1,105 @@
+package ghhook
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// MaxHTTPBodyBytes is the largest body accepted by HTTPHandler. Github caps
+// webhook payloads at 25MB.
+var MaxHTTPBodyBytes int64 = 25 << 20
+
+// HTTPHandler returns a http.Handler that dispatches webhooks through
+// DefaultHandler, for running ghhook in a long-lived server instead of Lambda.
+//
+// Example:
+//
+//	ghhook.Metrics = prometheusSink
+//
+//	mux := http.NewServeMux()
+//	mux.Handle("/", ghhook.HTTPHandler())
+//	mux.Handle("/metrics", prometheusSink)
+//	http.ListenAndServe(":8080", mux)
+func HTTPHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		req, err := ProxyRequestFromHTTP(r)
+		if err != nil {
+			writeHTTPResponse(w, errorResponse(err))
+			return
+		}
+
+		resp, err := DefaultHandler(req)
+		if err != nil {
+			resp = errorResponse(err)
+		}
+
+		writeHTTPResponse(w, resp)
+	})
+}
+
+// ProxyRequestFromHTTP converts the http request into the
+// APIGatewayProxyRequest received by DefaultHandler. Headers with multiple
+// values are joined with a comma, and the source ip is the remote address of
+// the request.
+func ProxyRequestFromHTTP(r *http.Request) (*events.APIGatewayProxyRequest, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, MaxHTTPBodyBytes))
+	if err != nil {
+		return nil, &ErrBadRequest{Reason: fmt.Sprintf("reading body: %v", err)}
+	}
+
+	headers := make(map[string]string, len(r.Header))
+	for k, v := range r.Header {
+		headers[k] = strings.Join(v, ",")
+	}
+
+	sourceIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		sourceIP = host
+	}
+
+	req := &events.APIGatewayProxyRequest{
+		Path:       r.URL.Path,
+		HTTPMethod: r.Method,
+		Headers:    headers,
+		Body:       string(body),
+	}
+	req.RequestContext.Identity.SourceIP = sourceIP
+
+	return req, nil
+}
+
+// errorResponse returns the response of ErrorResponseFn for the error, or a
+// plain 500 if it fails too.
+func errorResponse(err error) *events.APIGatewayProxyResponse {
+	resp, respErr := ErrorResponseFn(err)
+	if respErr != nil || resp == nil {
+		return &events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError, Body: err.Error()}
+	}
+
+	return resp
+}
+
+func writeHTTPResponse(w http.ResponseWriter, resp *events.APIGatewayProxyResponse) {
+	for k, v := range resp.Headers {
+		w.Header().Set(k, v)
+	}
+
+	statusCode := resp.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	w.WriteHeader(statusCode)
+	w.Write([]byte(resp.Body))
+}
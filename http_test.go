@@ -0,0 +1,69 @@
+package ghhook
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	Convey("HTTPHandler", t, func() {
+		server := httptest.NewServer(HTTPHandler())
+		Reset(func() {
+			server.Close()
+			ResetHandlers()
+		})
+
+		post := func(event, body string) *http.Response {
+			req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+			if event != "" {
+				req.Header.Set("X-GitHub-Event", event)
+			}
+			req.Header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+
+			resp, err := http.DefaultClient.Do(req)
+			So(err, ShouldBeNil)
+			return resp
+		}
+
+		Convey("It dispatches the request through DefaultHandler", func() {
+			EventHandler(PullRequestEvent, func(e interface{}) (*Response, error) {
+				return &Response{StatusCode: 201, Headers: map[string]string{"X-Test": "yes"}, Body: "handled"}, nil
+			})
+			SourceIPAllowlist = &IPAllowlist{}
+			Reset(func() { SourceIPAllowlist = nil })
+
+			resp := post("pull_request", PullRequestProxyRequest.Body)
+			body, _ := ioutil.ReadAll(resp.Body)
+
+			So(resp.StatusCode, ShouldEqual, 403)
+			So(string(body), ShouldContainSubstring, "127.0.0.1")
+
+			SourceIPAllowlist = nil
+			resp = post("pull_request", PullRequestProxyRequest.Body)
+			body, _ = ioutil.ReadAll(resp.Body)
+
+			So(resp.StatusCode, ShouldEqual, 201)
+			So(resp.Header.Get("X-Test"), ShouldEqual, "yes")
+			So(string(body), ShouldEqual, "handled")
+		})
+
+		Convey("It returns error responses", func() {
+			resp := post("", "{}")
+
+			So(resp.StatusCode, ShouldEqual, 400)
+			So(resp.Header.Get("Content-Type"), ShouldEqual, "application/json")
+		})
+
+		Convey("It only accepts POST", func() {
+			resp, err := http.Get(server.URL)
+
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 405)
+		})
+	})
+}
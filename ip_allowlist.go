@@ -0,0 +1,159 @@
+package ghhook
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// IPAllowlist rejects requests whose source ip is not in any of the allowed
+// networks.
+type IPAllowlist struct {
+	Networks []*net.IPNet
+
+	// TrustedProxies are the networks of proxies in front of the deployment, ie
+	// a load balancer. When the source ip is a trusted proxy, the client ip is
+	// taken from the 'X-Forwarded-For' header instead.
+	TrustedProxies []*net.IPNet
+}
+
+// SourceIPError is returned by IPAllowlist when it rejects a request.
+type SourceIPError struct {
+	IP string
+}
+
+func (e *SourceIPError) Error() string {
+	return fmt.Sprintf("ERROR: source ip '%s' is not allowed", e.IP)
+}
+
+// StatusCode returns the status code of the response for the error.
+func (e *SourceIPError) StatusCode() int {
+	return http.StatusForbidden
+}
+
+var (
+	// SourceIPAllowlist is used by DefaultHandler to reject requests that don't
+	// come from the allowed networks. It's disabled when nil.
+	//
+	// Requests republished over SNS or EventBridge have no source ip, so it
+	// should not be set when using SNSHandler or EventBridgeHandler.
+	SourceIPAllowlist *IPAllowlist
+)
+
+// githubMeta is the snapshot of https://api.github.com/meta shipped with this
+// library. It's embedded so it's found wherever the library is vendored.
+//
+//go:embed github-meta.json
+var githubMeta []byte
+
+// NewIPAllowlist returns an IPAllowlist that allows the given CIDRs.
+func NewIPAllowlist(cidrs []string) (*IPAllowlist, error) {
+	networks, err := parseCIDRs(cidrs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IPAllowlist{Networks: networks}, nil
+}
+
+// LoadGithubHookAllowlist returns an IPAllowlist that allows the 'hooks' CIDRs
+// of a file in the format of https://api.github.com/meta. The snapshot shipped
+// with this library is used when path is empty.
+func LoadGithubHookAllowlist(path string) (*IPAllowlist, error) {
+	b := githubMeta
+	if path != "" {
+		var err error
+		if b, err = ioutil.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var meta struct {
+		Hooks []string `json:"hooks"`
+	}
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return nil, err
+	}
+
+	if len(meta.Hooks) == 0 {
+		return nil, fmt.Errorf("ERROR: no 'hooks' in '%s'", path)
+	}
+
+	return NewIPAllowlist(meta.Hooks)
+}
+
+// TrustProxies sets the networks of the trusted proxies.
+func (a *IPAllowlist) TrustProxies(cidrs []string) error {
+	networks, err := parseCIDRs(cidrs)
+	if err != nil {
+		return err
+	}
+
+	a.TrustedProxies = networks
+	return nil
+}
+
+// Check returns a *SourceIPError if the client ip of the request is not
+// allowed.
+func (a *IPAllowlist) Check(r *events.APIGatewayProxyRequest) error {
+	forwardedFor, _ := headerValue(r.Headers, "X-Forwarded-For")
+
+	ip := clientIP(r.RequestContext.Identity.SourceIP, forwardedFor, a.TrustedProxies)
+	if !containsIP(a.Networks, ip) {
+		return &SourceIPError{IP: ip}
+	}
+
+	return nil
+}
+
+// clientIP walks the 'X-Forwarded-For' chain from right to left while the
+// addresses are trusted proxies, since only those are known to append the real
+// client address.
+func clientIP(sourceIP, forwardedFor string, trusted []*net.IPNet) string {
+	ip := sourceIP
+	if forwardedFor == "" {
+		return ip
+	}
+
+	hops := strings.Split(forwardedFor, ",")
+	for i := len(hops) - 1; i >= 0 && containsIP(trusted, ip); i-- {
+		ip = strings.TrimSpace(hops[i])
+	}
+
+	return ip
+}
+
+func containsIP(networks []*net.IPNet, s string) bool {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, err
+		}
+
+		networks = append(networks, n)
+	}
+
+	return networks, nil
+}
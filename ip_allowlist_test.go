@@ -0,0 +1,82 @@
+package ghhook
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIPAllowlist(t *testing.T) {
+	Convey("IPAllowlist", t, func() {
+		allowlist, err := LoadGithubHookAllowlist("")
+		So(err, ShouldBeNil)
+
+		request := func(sourceIP, forwardedFor string) *events.APIGatewayProxyRequest {
+			r := &events.APIGatewayProxyRequest{Headers: map[string]string{}}
+			r.RequestContext.Identity.SourceIP = sourceIP
+			if forwardedFor != "" {
+				r.Headers["X-Forwarded-For"] = forwardedFor
+			}
+			return r
+		}
+
+		Convey("It allows Github hook ranges", func() {
+			So(allowlist.Check(request("192.30.252.1", "")), ShouldBeNil)
+			So(allowlist.Check(request("2606:50c0::1", "")), ShouldBeNil)
+		})
+
+		Convey("It rejects other ips", func() {
+			err := allowlist.Check(request("10.0.0.1", ""))
+			So(err, ShouldResemble, &SourceIPError{IP: "10.0.0.1"})
+		})
+
+		Convey("It ignores X-Forwarded-For from untrusted sources", func() {
+			So(allowlist.Check(request("10.0.0.1", "192.30.252.1")), ShouldNotBeNil)
+		})
+
+		Convey("It uses X-Forwarded-For behind trusted proxies", func() {
+			So(allowlist.TrustProxies([]string{"10.0.0.0/8"}), ShouldBeNil)
+
+			So(allowlist.Check(request("10.0.0.1", "1.2.3.4, 192.30.252.1, 10.0.0.2")), ShouldBeNil)
+			So(allowlist.Check(request("10.0.0.1", "192.30.252.1, 1.2.3.4")), ShouldNotBeNil)
+		})
+
+		Convey("It loads the embedded snapshot from any directory", func() {
+			wd, _ := os.Getwd()
+			So(os.Chdir(os.TempDir()), ShouldBeNil)
+			Reset(func() { os.Chdir(wd) })
+
+			allowlist, err := LoadGithubHookAllowlist("")
+			So(err, ShouldBeNil)
+			So(allowlist.Check(request("192.30.252.1", "")), ShouldBeNil)
+		})
+
+		Convey("It loads the hooks of a file", func() {
+			f, err := ioutil.TempFile("", "github-meta")
+			So(err, ShouldBeNil)
+			Reset(func() { os.Remove(f.Name()) })
+			f.WriteString(`{"hooks": ["10.0.0.0/8"]}`)
+			f.Close()
+
+			allowlist, err := LoadGithubHookAllowlist(f.Name())
+			So(err, ShouldBeNil)
+			So(allowlist.Check(request("10.0.0.1", "")), ShouldBeNil)
+			So(allowlist.Check(request("192.30.252.1", "")), ShouldNotBeNil)
+		})
+
+		Convey("DefaultHandler rejects requests from other ips", func() {
+			SourceIPAllowlist = allowlist
+			Reset(func() { SourceIPAllowlist = nil })
+
+			r := request("10.0.0.1", "")
+			r.Headers["X-GitHub-Event"] = "pull_request"
+
+			resp, err := DefaultHandler(r)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 403)
+		})
+	})
+}
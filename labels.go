@@ -0,0 +1,124 @@
+package ghhook
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OnLabelAdded runs the InputFn only for 'labeled' actions of issues and pull
+// requests that add any of the given labels. Label names are case
+// insensitive.
+func OnLabelAdded(names ...string) HandlerOption {
+	return labelActionOption("labeled", names)
+}
+
+// OnLabelRemoved runs the InputFn only for 'unlabeled' actions of issues and
+// pull requests that remove any of the given labels.
+func OnLabelRemoved(names ...string) HandlerOption {
+	return labelActionOption("unlabeled", names)
+}
+
+// WithAnyLabel runs the InputFn only for issues and pull requests that
+// currently have any of the given labels.
+func WithAnyLabel(names ...string) HandlerOption {
+	return currentLabelsOption(func(labels []string) string {
+		for _, name := range names {
+			if containsLabel(labels, name) {
+				return ""
+			}
+		}
+
+		return fmt.Sprintf("labels '%v' have none of '%v'", labels, names)
+	})
+}
+
+// WithAllLabels runs the InputFn only for issues and pull requests that
+// currently have all of the given labels.
+func WithAllLabels(names ...string) HandlerOption {
+	return currentLabelsOption(func(labels []string) string {
+		for _, name := range names {
+			if !containsLabel(labels, name) {
+				return fmt.Sprintf("labels '%v' don't have '%s'", labels, name)
+			}
+		}
+
+		return ""
+	})
+}
+
+// WithoutLabels doesn't run the InputFn for issues and pull requests that
+// currently have any of the given labels.
+func WithoutLabels(names ...string) HandlerOption {
+	return currentLabelsOption(func(labels []string) string {
+		for _, name := range names {
+			if containsLabel(labels, name) {
+				return fmt.Sprintf("label '%s' is excluded", name)
+			}
+		}
+
+		return ""
+	})
+}
+
+func labelActionOption(action string, names []string) HandlerOption {
+	return func(c *handlerConfig) {
+		c.add(mapCondition(func(m map[string]interface{}) string {
+			if a, _ := m["action"].(string); a != action {
+				return fmt.Sprintf("action '%v' is not '%s'", m["action"], action)
+			}
+
+			label, _ := lookupPath(m, "label.name").(string)
+			if !containsLabel(names, label) {
+				return fmt.Sprintf("%s label '%s' is not in '%v'", action, label, names)
+			}
+
+			return ""
+		}))
+	}
+}
+
+// currentLabelsOption drops events that are not of issues or pull requests,
+// otherwise check returns the drop reason given their current labels.
+func currentLabelsOption(check func(labels []string) string) HandlerOption {
+	return func(c *handlerConfig) {
+		c.add(mapCondition(func(m map[string]interface{}) string {
+			labels, ok := eventLabels(m)
+			if !ok {
+				return "event has no issue or pull request"
+			}
+
+			return check(labels)
+		}))
+	}
+}
+
+// eventLabels returns the names of the current labels of the issue or pull
+// request of the event.
+func eventLabels(m map[string]interface{}) ([]string, bool) {
+	for _, key := range []string{"pull_request", "issue"} {
+		if _, ok := m[key].(map[string]interface{}); !ok {
+			continue
+		}
+
+		labels := []string{}
+		for _, v := range ResolvePath(m, key+".labels[*].name") {
+			if name, ok := v.(string); ok {
+				labels = append(labels, name)
+			}
+		}
+
+		return labels, true
+	}
+
+	return nil, false
+}
+
+func containsLabel(labels []string, name string) bool {
+	for _, label := range labels {
+		if strings.EqualFold(label, name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,72 @@
+package ghhook
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLabelOptions(t *testing.T) {
+	Convey("Label options", t, func() {
+		Reset(func() { ResetHandlers() })
+
+		var fn InputFn = func(e interface{}) (*Response, error) { return localSuccessResp("ok") }
+
+		labeled := &events.APIGatewayProxyRequest{
+			Headers: map[string]string{"X-GitHub-Event": "pull_request"},
+			Body: `{
+				"action": "labeled",
+				"label": {"name": "needs-qa"},
+				"pull_request": {"labels": [{"name": "bug"}, {"name": "needs-qa"}]}
+			}`,
+		}
+
+		issue := &events.APIGatewayProxyRequest{
+			Headers: map[string]string{"X-GitHub-Event": "issues"},
+			Body:    `{"action": "opened", "issue": {"labels": [{"name": "Security"}]}}`,
+		}
+
+		dispatch := func(r *events.APIGatewayProxyRequest, event Event, opts ...HandlerOption) string {
+			EventHandlerWithOptions(event, fn, opts...)
+
+			resp, err := DefaultHandler(r)
+			So(err, ShouldBeNil)
+			return resp.Body
+		}
+
+		Convey("OnLabelAdded", func() {
+			So(dispatch(labeled, PullRequestEvent, OnLabelAdded("needs-qa")), ShouldEqual, "ok")
+			So(dispatch(labeled, PullRequestEvent, OnLabelAdded("security")), ShouldEqual,
+				"Dropping unmatched event for option: labeled label 'needs-qa' is not in '[security]'")
+		})
+
+		Convey("OnLabelRemoved", func() {
+			So(dispatch(labeled, PullRequestEvent, OnLabelRemoved("needs-qa")), ShouldEqual,
+				"Dropping unmatched event for option: action 'labeled' is not 'unlabeled'")
+		})
+
+		Convey("WithAnyLabel", func() {
+			So(dispatch(issue, IssuesEvent, WithAnyLabel("security", "bug")), ShouldEqual, "ok")
+			So(dispatch(issue, IssuesEvent, WithAnyLabel("bug")), ShouldEqual,
+				"Dropping unmatched event for option: labels '[Security]' have none of '[bug]'")
+		})
+
+		Convey("WithAllLabels", func() {
+			So(dispatch(labeled, PullRequestEvent, WithAllLabels("bug", "needs-qa")), ShouldEqual, "ok")
+			So(dispatch(labeled, PullRequestEvent, WithAllLabels("bug", "security")), ShouldEqual,
+				"Dropping unmatched event for option: labels '[bug needs-qa]' don't have 'security'")
+		})
+
+		Convey("WithoutLabels", func() {
+			So(dispatch(issue, IssuesEvent, WithoutLabels("wontfix")), ShouldEqual, "ok")
+			So(dispatch(labeled, PullRequestEvent, WithoutLabels("bug")), ShouldEqual,
+				"Dropping unmatched event for option: label 'bug' is excluded")
+		})
+
+		Convey("It drops events without issue or pull request", func() {
+			So(dispatch(CreateEventProxyRequest, CreateEvent, WithAnyLabel("bug")), ShouldEqual,
+				"Dropping unmatched event for option: event has no issue or pull request")
+		})
+	})
+}
@@ -0,0 +1,86 @@
+package ghhook
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+// Logger receives a record of every delivery handled by DefaultHandler.
+type Logger interface {
+	LogDelivery(rec *DeliveryRecord)
+}
+
+// DeliveryLogger is used by DefaultHandler to log deliveries. Logging is
+// disabled when it's nil.
+var DeliveryLogger Logger
+
+// SlogLogger is a Logger that writes structured records with log/slog.
+// Failed deliveries, and deliveries AuditLog failed to record, are logged at
+// error level, others at info level.
+type SlogLogger struct {
+	Logger *slog.Logger
+}
+
+// NewSlogLogger returns a SlogLogger that writes to the given logger.
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	return &SlogLogger{Logger: logger}
+}
+
+// NewJSONLogger returns a SlogLogger that writes a JSON object per delivery to
+// w, ie os.Stdout for CloudWatch Logs Insights.
+func NewJSONLogger(w io.Writer) *SlogLogger {
+	return NewSlogLogger(slog.New(slog.NewJSONHandler(w, nil)))
+}
+
+func (l *SlogLogger) LogDelivery(rec *DeliveryRecord) {
+	level := slog.LevelInfo
+	if rec.Outcome == OutcomeFailed || rec.AuditError != "" {
+		level = slog.LevelError
+	}
+
+	attrs := []slog.Attr{
+		slog.String("delivery_id", rec.DeliveryID),
+		slog.String("event", string(rec.Event)),
+		slog.String("action", rec.Action),
+		slog.String("repository", rec.Repository),
+		slog.String("sender", rec.Sender),
+		slog.String("outcome", string(rec.Outcome)),
+		slog.Int("status_code", rec.StatusCode),
+		slog.Float64("duration_ms", durationMS(rec.Duration)),
+		slog.Int("handlers_run", len(rec.Handlers)),
+	}
+
+	if rec.Replay {
+		attrs = append(attrs, slog.Bool("replay", true))
+	}
+
+	if rec.Error != "" {
+		attrs = append(attrs, slog.String("error", rec.Error))
+	}
+
+	if rec.AuditError != "" {
+		attrs = append(attrs, slog.String("audit_error", rec.AuditError))
+	}
+
+	if len(rec.Handlers) > 0 {
+		handlers := make([]map[string]interface{}, len(rec.Handlers))
+		for i, h := range rec.Handlers {
+			handlers[i] = map[string]interface{}{
+				"index":       h.Index,
+				"outcome":     h.Outcome,
+				"duration_ms": durationMS(h.Duration),
+			}
+			if h.Reason != "" {
+				handlers[i]["reason"] = h.Reason
+			}
+			if h.Error != "" {
+				handlers[i]["error"] = h.Error
+			}
+		}
+
+		attrs = append(attrs, slog.Any("handlers", handlers))
+	}
+
+	l.Logger.LogAttrs(context.Background(), level, "github delivery", attrs...)
+}
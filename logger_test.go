@@ -0,0 +1,95 @@
+package ghhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDeliveryLogger(t *testing.T) {
+	Convey("DeliveryLogger", t, func() {
+		var buf bytes.Buffer
+		DeliveryLogger = NewJSONLogger(&buf)
+		Reset(func() {
+			DeliveryLogger = nil
+			ResetHandlers()
+		})
+
+		r := &events.APIGatewayProxyRequest{
+			Headers: map[string]string{
+				"X-GitHub-Event":    "pull_request",
+				"X-GitHub-Delivery": "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+			},
+			Body: PullRequestProxyRequest.Body,
+		}
+
+		record := func() map[string]interface{} {
+			var m map[string]interface{}
+			So(json.Unmarshal(buf.Bytes(), &m), ShouldBeNil)
+			return m
+		}
+
+		Convey("It logs successful deliveries", func() {
+			EventHandler(PullRequestEvent, func(e interface{}) (*Response, error) { return localSuccessResp("ok") })
+			DefaultHandler(r)
+
+			m := record()
+			So(m["level"], ShouldEqual, "INFO")
+			So(m["msg"], ShouldEqual, "github delivery")
+			So(m["delivery_id"], ShouldEqual, "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+			So(m["event"], ShouldEqual, "pull_request")
+			So(m["action"], ShouldEqual, "opened")
+			So(m["repository"], ShouldEqual, "baxterthehacker/public-repo")
+			So(m["sender"], ShouldEqual, "baxterthehacker")
+			So(m["outcome"], ShouldEqual, "succeeded")
+			So(m["status_code"], ShouldEqual, 200)
+			So(m["handlers_run"], ShouldEqual, 1)
+			So(m, ShouldContainKey, "duration_ms")
+		})
+
+		Convey("It logs unregistered events", func() {
+			DefaultHandler(r)
+
+			m := record()
+			So(m["outcome"], ShouldEqual, "dropped_unregistered")
+			So(m["action"], ShouldEqual, "opened")
+			So(m["handlers_run"], ShouldEqual, 0)
+		})
+
+		Convey("It logs the result of every InputFn", func() {
+			On(PullRequestEvent, PullRequestClosed).Handle(func(e interface{}) (*Response, error) { return localSuccessResp("ok") })
+			EventHandler(PullRequestEvent, func(e interface{}) (*Response, error) { return nil, errors.New("failed") })
+			DefaultHandler(r)
+
+			m := record()
+			So(m["level"], ShouldEqual, "ERROR")
+			So(m["outcome"], ShouldEqual, "failed")
+			So(m["error"], ShouldEqual, "failed")
+
+			handlers := m["handlers"].([]interface{})
+			So(len(handlers), ShouldEqual, 2)
+			So(handlers[0].(map[string]interface{})["outcome"], ShouldEqual, "dropped")
+			So(handlers[0].(map[string]interface{})["reason"], ShouldEqual, "Dropping unmatched event for option: action 'opened' is not in '[closed]'")
+			So(handlers[1].(map[string]interface{})["outcome"], ShouldEqual, "failed")
+		})
+
+		Convey("It logs deliveries dropped by all filters", func() {
+			On(PullRequestEvent, PullRequestClosed).Handle(func(e interface{}) (*Response, error) { return localSuccessResp("ok") })
+			DefaultHandler(r)
+
+			So(record()["outcome"], ShouldEqual, "dropped_filtered")
+		})
+
+		Convey("It logs rejected deliveries", func() {
+			DefaultHandler(&events.APIGatewayProxyRequest{})
+
+			m := record()
+			So(m["outcome"], ShouldEqual, "rejected")
+			So(m["status_code"], ShouldEqual, 400)
+		})
+	})
+}
@@ -0,0 +1,190 @@
+package ghhook
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricUnit is the unit of a Metric.
+type MetricUnit string
+
+const (
+	UnitCount        MetricUnit = "Count"
+	UnitMilliseconds MetricUnit = "Milliseconds"
+)
+
+// Metric is a single measurement emitted for a delivery. Counters have a value
+// of 1 and UnitCount, durations are in UnitMilliseconds.
+type Metric struct {
+	Name       string
+	Unit       MetricUnit
+	Value      float64
+	Dimensions map[string]string
+}
+
+// MetricsSink receives the metrics of every delivery handled by
+// DefaultHandler.
+type MetricsSink interface {
+	Emit(metrics []Metric)
+}
+
+// Metrics is used by DefaultHandler to emit metrics. Metrics are disabled when
+// it's nil.
+var Metrics MetricsSink
+
+// deliveryMetrics returns the metrics of the delivery:
+//
+//   - deliveries_received, and deliveries_<outcome>, ie
+//     deliveries_dropped_filtered, counters and delivery_duration, with the
+//     event, action and repository dimensions.
+//   - handler_<outcome>, ie handler_succeeded, counters and handler_duration
+//     for every InputFn that ran, with an additional handler dimension holding
+//     its index in Handlers.
+func deliveryMetrics(rec *DeliveryRecord) []Metric {
+	dims := map[string]string{
+		"event":      dimensionValue(string(rec.Event)),
+		"action":     dimensionValue(rec.Action),
+		"repository": dimensionValue(rec.Repository),
+	}
+
+	metrics := []Metric{
+		{Name: "deliveries_received", Unit: UnitCount, Value: 1, Dimensions: dims},
+		{Name: "deliveries_" + string(rec.Outcome), Unit: UnitCount, Value: 1, Dimensions: dims},
+		{Name: "delivery_duration", Unit: UnitMilliseconds, Value: durationMS(rec.Duration), Dimensions: dims},
+	}
+
+	for _, h := range rec.Handlers {
+		handlerDims := map[string]string{"handler": strconv.Itoa(h.Index)}
+		for k, v := range dims {
+			handlerDims[k] = v
+		}
+
+		metrics = append(metrics,
+			Metric{Name: "handler_" + string(h.Outcome), Unit: UnitCount, Value: 1, Dimensions: handlerDims},
+			Metric{Name: "handler_duration", Unit: UnitMilliseconds, Value: durationMS(h.Duration), Dimensions: handlerDims},
+		)
+	}
+
+	return metrics
+}
+
+// dimensionValue replaces empty values, ie the action of a push event, since
+// dimensions can't be empty.
+func dimensionValue(v string) string {
+	if v == "" {
+		return "none"
+	}
+
+	return v
+}
+
+// EMFSink is a MetricsSink that writes metrics in CloudWatch Embedded Metric
+// Format, so they are extracted from the logs of the Lambda without running
+// an agent. Metrics with the same dimensions are written as one JSON line.
+type EMFSink struct {
+	Namespace string
+	Writer    io.Writer
+
+	mu sync.Mutex
+}
+
+// NewEMFSink returns an EMFSink that writes to stdout.
+func NewEMFSink(namespace string) *EMFSink {
+	return &EMFSink{Namespace: namespace, Writer: os.Stdout}
+}
+
+type emfMetric struct {
+	Name string     `json:"Name"`
+	Unit MetricUnit `json:"Unit"`
+}
+
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+type emfMetadata struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+func (s *EMFSink) Emit(metrics []Metric) {
+	var keys []string
+	groups := map[string][]Metric{}
+	for _, m := range metrics {
+		key := dimensionsKey(m.Dimensions)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], m)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		b, err := json.Marshal(s.document(groups[key]))
+		if err != nil {
+			continue
+		}
+
+		s.Writer.Write(append(b, '\n'))
+	}
+}
+
+// document returns the EMF document of metrics that share dimensions.
+func (s *EMFSink) document(metrics []Metric) map[string]interface{} {
+	dims := metrics[0].Dimensions
+
+	names := make([]string, 0, len(dims))
+	for name := range dims {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	directive := emfDirective{Namespace: s.Namespace, Dimensions: [][]string{names}}
+	doc := map[string]interface{}{}
+	for name, value := range dims {
+		doc[name] = value
+	}
+
+	for _, m := range metrics {
+		// the same metric can be emitted more than once, ie handler_duration,
+		// in which case EMF expects an array of values.
+		if v, ok := doc[m.Name]; ok {
+			if values, ok := v.([]float64); ok {
+				doc[m.Name] = append(values, m.Value)
+			} else {
+				doc[m.Name] = []float64{v.(float64), m.Value}
+			}
+			continue
+		}
+
+		doc[m.Name] = m.Value
+		directive.Metrics = append(directive.Metrics, emfMetric{Name: m.Name, Unit: m.Unit})
+	}
+
+	doc["_aws"] = emfMetadata{
+		Timestamp:         now().UnixNano() / int64(time.Millisecond),
+		CloudWatchMetrics: []emfDirective{directive},
+	}
+
+	return doc
+}
+
+func dimensionsKey(dims map[string]string) string {
+	pairs := make([]string, 0, len(dims))
+	for k, v := range dims {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "\x00")
+}
@@ -0,0 +1,123 @@
+package ghhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// recordingSink keeps the emitted metrics.
+type recordingSink struct {
+	metrics []Metric
+}
+
+func (s *recordingSink) Emit(metrics []Metric) {
+	s.metrics = append(s.metrics, metrics...)
+}
+
+func (s *recordingSink) names() []string {
+	var names []string
+	for _, m := range s.metrics {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+func TestMetrics(t *testing.T) {
+	Convey("Metrics", t, func() {
+		sink := &recordingSink{}
+		Metrics = sink
+		Reset(func() {
+			Metrics = nil
+			ResetHandlers()
+		})
+
+		Convey("It emits delivery and handler metrics", func() {
+			On(PullRequestEvent, PullRequestClosed).Handle(func(e interface{}) (*Response, error) { return localSuccessResp("ok") })
+			EventHandler(PullRequestEvent, func(e interface{}) (*Response, error) { return localSuccessResp("ok") })
+			DefaultHandler(PullRequestProxyRequest)
+
+			So(sink.names(), ShouldResemble, []string{
+				"deliveries_received", "deliveries_succeeded", "delivery_duration",
+				"handler_dropped", "handler_duration",
+				"handler_succeeded", "handler_duration",
+			})
+
+			So(sink.metrics[0].Dimensions, ShouldResemble, map[string]string{
+				"event":      "pull_request",
+				"action":     "opened",
+				"repository": "baxterthehacker/public-repo",
+			})
+			So(sink.metrics[5].Dimensions["handler"], ShouldEqual, "1")
+		})
+
+		Convey("It emits unregistered deliveries", func() {
+			DefaultHandler(CreateEventProxyRequest)
+
+			So(sink.names(), ShouldResemble, []string{"deliveries_received", "deliveries_dropped_unregistered", "delivery_duration"})
+			So(sink.metrics[0].Dimensions["action"], ShouldEqual, "none")
+		})
+	})
+
+	Convey("EMFSink", t, func() {
+		current := time.Date(2018, 4, 9, 0, 0, 0, 0, time.UTC)
+		now = func() time.Time { return current }
+		Reset(func() { now = time.Now })
+
+		var buf bytes.Buffer
+		sink := &EMFSink{Namespace: "ghhook", Writer: &buf}
+
+		dims := map[string]string{"event": "push", "action": "none", "repository": "a/b"}
+		handlerDims := map[string]string{"event": "push", "action": "none", "repository": "a/b", "handler": "0"}
+		sink.Emit([]Metric{
+			{Name: "deliveries_received", Unit: UnitCount, Value: 1, Dimensions: dims},
+			{Name: "delivery_duration", Unit: UnitMilliseconds, Value: 12.5, Dimensions: dims},
+			{Name: "handler_duration", Unit: UnitMilliseconds, Value: 10, Dimensions: handlerDims},
+		})
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		So(len(lines), ShouldEqual, 2)
+
+		var doc map[string]interface{}
+		So(json.Unmarshal([]byte(lines[0]), &doc), ShouldBeNil)
+		So(doc["event"], ShouldEqual, "push")
+		So(doc["deliveries_received"], ShouldEqual, 1)
+		So(doc["delivery_duration"], ShouldEqual, 12.5)
+
+		aws := doc["_aws"].(map[string]interface{})
+		So(aws["Timestamp"], ShouldEqual, 1523232000000)
+
+		directive := aws["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+		So(directive["Namespace"], ShouldEqual, "ghhook")
+		So(directive["Dimensions"], ShouldResemble, []interface{}{[]interface{}{"action", "event", "repository"}})
+		So(directive["Metrics"], ShouldResemble, []interface{}{
+			map[string]interface{}{"Name": "deliveries_received", "Unit": "Count"},
+			map[string]interface{}{"Name": "delivery_duration", "Unit": "Milliseconds"},
+		})
+
+		So(json.Unmarshal([]byte(lines[1]), &doc), ShouldBeNil)
+		So(doc["handler"], ShouldEqual, "0")
+	})
+}
+
+func TestEMFSinkRepeatedMetrics(t *testing.T) {
+	Convey("EMFSink writes repeated metrics as arrays", t, func() {
+		var buf bytes.Buffer
+		sink := &EMFSink{Namespace: "ghhook", Writer: &buf}
+
+		dims := map[string]string{"event": "push"}
+		sink.Emit([]Metric{
+			{Name: "handler_duration", Unit: UnitMilliseconds, Value: 1, Dimensions: dims},
+			{Name: "handler_duration", Unit: UnitMilliseconds, Value: 2, Dimensions: dims},
+			{Name: "handler_duration", Unit: UnitMilliseconds, Value: 3, Dimensions: dims},
+		})
+
+		var doc map[string]interface{}
+		So(json.Unmarshal(buf.Bytes(), &doc), ShouldBeNil)
+		So(doc["handler_duration"], ShouldResemble, []interface{}{1.0, 2.0, 3.0})
+	})
+}
@@ -0,0 +1,73 @@
+package ghhook
+
+import (
+	"fmt"
+)
+
+// HandlerOption restricts when an InputFn registered with
+// EventHandlerWithOptions runs.
+type HandlerOption func(*handlerConfig)
+
+// handlerConfig is built from the HandlerOption of a single InputFn.
+type handlerConfig struct {
+	conditions  []condition
+	paths       *pathFilter
+	includeBots bool
+}
+
+// condition returns the reason the event is dropped, or an empty string if
+// the InputFn should run.
+type condition func(i interface{}) (string, error)
+
+func (c *handlerConfig) add(cond condition) {
+	c.conditions = append(c.conditions, cond)
+}
+
+// EventHandlerWithOptions is similar to EventHandler with addition of checking
+// the given options before the InputFn runs. Events that don't match are
+// dropped and responded with the reason, like the other filters.
+//
+// Example:
+//
+//	ghhook.EventHandlerWithOptions(
+//	  ghhook.PushEvent,
+//	  func(e interface{}) (*ghhook.Response, error) { return nil, nil },
+//	  ghhook.InRepos("WalkerAndCoBrandsInc/api-*"),
+//	  ghhook.ExceptRepos("*/docs"),
+//	)
+func EventHandlerWithOptions(event Event, fn InputFn, opts ...HandlerOption) {
+	config := &handlerConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	wrappedFn := func(i interface{}) (*Response, error) {
+		for _, cond := range config.conditions {
+			reason, err := traceFilter(i, "option", func() (string, error) { return cond(i) })
+			if err != nil {
+				return nil, err
+			}
+
+			if reason != "" {
+				return dropEvent(i, fmt.Sprintf("Dropping unmatched event for option: %s", reason))
+			}
+		}
+
+		return fn(i)
+	}
+
+	Handlers[event] = append(Handlers[event], checkSenders(wrappedFn, config.includeBots))
+}
+
+// mapCondition returns a condition that's evaluated on the generic map of the
+// event.
+func mapCondition(fn func(m map[string]interface{}) string) condition {
+	return func(i interface{}) (string, error) {
+		m, err := eventMap(i)
+		if err != nil {
+			return "", err
+		}
+
+		return fn(m), nil
+	}
+}
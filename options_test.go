@@ -0,0 +1,70 @@
+package ghhook
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEventHandlerWithOptions(t *testing.T) {
+	Convey("EventHandlerWithOptions", t, func() {
+		Reset(func() { ResetHandlers() })
+
+		var fn InputFn = func(e interface{}) (*Response, error) { return localSuccessResp("ok") }
+
+		dispatch := func(opts ...HandlerOption) string {
+			EventHandlerWithOptions(PullRequestEvent, fn, opts...)
+
+			resp, err := DefaultHandler(PullRequestProxyRequest)
+			So(err, ShouldBeNil)
+			return resp.Body
+		}
+
+		Convey("It runs fn without options", func() {
+			So(dispatch(), ShouldEqual, "ok")
+		})
+
+		Convey("InRepos", func() {
+			So(dispatch(InRepos("BaxterTheHacker/public-*")), ShouldEqual, "ok")
+		})
+
+		Convey("InRepos drops other repositories", func() {
+			So(dispatch(InRepos("WalkerAndCoBrandsInc/*", "*/private-repo")), ShouldEqual,
+				"Dropping unmatched event for option: repository 'baxterthehacker/public-repo' doesn't match '[WalkerAndCoBrandsInc/* */private-repo]'")
+		})
+
+		Convey("ExceptRepos", func() {
+			So(dispatch(ExceptRepos("*/docs")), ShouldEqual, "ok")
+			So(dispatch(ExceptRepos("*/public-repo")), ShouldEqual,
+				"Dropping unmatched event for option: repository 'baxterthehacker/public-repo' is excluded by '[*/public-repo]'")
+		})
+
+		Convey("InOwners", func() {
+			So(dispatch(InOwners("walkerandcobrandsinc", "BAXTERTHEHACKER")), ShouldEqual, "ok")
+		})
+
+		Convey("InOwners drops other owners", func() {
+			So(dispatch(InOwners("WalkerAndCoBrandsInc")), ShouldEqual,
+				"Dropping unmatched event for option: owner 'baxterthehacker' is not in '[WalkerAndCoBrandsInc]'")
+		})
+
+		Convey("WithVisibility", func() {
+			So(dispatch(WithVisibility(VisibilityPublic)), ShouldEqual, "ok")
+		})
+
+		Convey("WithVisibility drops other visibilities", func() {
+			So(dispatch(WithVisibility(VisibilityPrivate, VisibilityFork)), ShouldEqual,
+				"Dropping unmatched event for option: repository 'baxterthehacker/public-repo' is not '[private fork]'")
+		})
+
+		Convey("ExceptVisibility", func() {
+			So(dispatch(ExceptVisibility(VisibilityFork, VisibilityArchived)), ShouldEqual, "ok")
+			So(dispatch(ExceptVisibility(VisibilityPublic)), ShouldEqual,
+				"Dropping unmatched event for option: repository 'baxterthehacker/public-repo' is 'public'")
+		})
+
+		Convey("It panics on invalid globs", func() {
+			So(func() { InRepos("[") }, ShouldPanic)
+		})
+	})
+}
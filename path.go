@@ -0,0 +1,155 @@
+package ghhook
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pathStep is a single step of a field path: either an object key, or an
+// array index. Wildcards match every key or element.
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// ParsePath validates a field path used by filters.
+//
+// Paths are object keys separated by dots, ie 'pull_request.base.ref'. Array
+// elements are selected with '[0]', or '[-1]' for the last one, and '[*]'
+// selects all of them, ie 'pull_request.labels[*].name'. A '*' key selects all
+// values of an object.
+func ParsePath(path string) error {
+	_, err := parsePath(path)
+	return err
+}
+
+func parsePath(path string) ([]pathStep, error) {
+	if path == "" {
+		return nil, fmt.Errorf("ERROR: empty path")
+	}
+
+	var steps []pathStep
+	for _, segment := range strings.Split(path, ".") {
+		key := segment
+		if i := strings.IndexByte(segment, '['); i >= 0 {
+			key = segment[:i]
+		}
+
+		if key == "" || strings.ContainsRune(key, ']') {
+			return nil, fmt.Errorf("ERROR: invalid path '%s'", path)
+		}
+
+		steps = append(steps, pathStep{key: key, wildcard: key == "*"})
+
+		for rest := segment[len(key):]; rest != ""; {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("ERROR: invalid path '%s'", path)
+			}
+
+			step := pathStep{isIndex: true}
+			if index := rest[1:end]; index == "*" {
+				step.wildcard = true
+			} else {
+				n, err := strconv.Atoi(index)
+				if err != nil {
+					return nil, fmt.Errorf("ERROR: invalid index '%s' in path '%s'", index, path)
+				}
+				step.index = n
+			}
+
+			steps = append(steps, step)
+			rest = rest[end+1:]
+		}
+	}
+
+	return steps, nil
+}
+
+// ResolvePath returns the values at the given path in the decoded JSON
+// object; see ParsePath for the syntax. It returns nil if the path is invalid
+// or there are no values at the path.
+func ResolvePath(m map[string]interface{}, path string) []interface{} {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil
+	}
+
+	return resolveSteps(m, steps)
+}
+
+func resolveSteps(v interface{}, steps []pathStep) []interface{} {
+	if len(steps) == 0 {
+		return []interface{}{v}
+	}
+
+	step, rest := steps[0], steps[1:]
+
+	if step.isIndex {
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil
+		}
+
+		if !step.wildcard {
+			i := step.index
+			if i < 0 {
+				i += len(arr)
+			}
+
+			if i < 0 || i >= len(arr) {
+				return nil
+			}
+
+			return resolveSteps(arr[i], rest)
+		}
+
+		var values []interface{}
+		for _, el := range arr {
+			values = append(values, resolveSteps(el, rest)...)
+		}
+
+		return values
+	}
+
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if !step.wildcard {
+		value, ok := obj[step.key]
+		if !ok {
+			return nil
+		}
+
+		return resolveSteps(value, rest)
+	}
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var values []interface{}
+	for _, key := range keys {
+		values = append(values, resolveSteps(obj[key], rest)...)
+	}
+
+	return values
+}
+
+// lookupPath returns the first value at the given path, or nil if there's
+// none.
+func lookupPath(m map[string]interface{}, path string) interface{} {
+	if values := ResolvePath(m, path); len(values) > 0 {
+		return values[0]
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package ghhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/github"
+)
+
+// ChangedFilesResolver returns the files changed by a pull request.
+type ChangedFilesResolver func(ctx context.Context, owner, repo string, number int) ([]string, error)
+
+// GithubChangedFilesResolver returns a ChangedFilesResolver that lists the
+// files of pull requests with the given client.
+func GithubChangedFilesResolver(client *github.Client) ChangedFilesResolver {
+	return func(ctx context.Context, owner, repo string, number int) ([]string, error) {
+		var files []string
+
+		opt := &github.ListOptions{PerPage: 100}
+		for {
+			commitFiles, resp, err := client.PullRequests.ListFiles(ctx, owner, repo, number, opt)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, f := range commitFiles {
+				files = append(files, f.GetFilename())
+			}
+
+			if resp.NextPage == 0 {
+				return files, nil
+			}
+
+			opt.Page = resp.NextPage
+		}
+	}
+}
+
+// pathFilter is shared by the path options of a handler, so includes and
+// excludes are evaluated together.
+type pathFilter struct {
+	includes globs
+	excludes globs
+	resolver ChangedFilesResolver
+}
+
+// OnPaths runs the InputFn only for push and pull_request events that change
+// a file matching any of the globs, ie 'terraform/**' or '**/*.proto'. '*'
+// doesn't match '/' while '**' does. It panics if any of the globs is invalid.
+//
+// Push events use the added, modified and removed files of their commits.
+// Pushes with no commits, ie of tags or deleted branches, have no changed
+// files. Github includes at most 2048 commits in push payloads, so pushes of
+// 2048 commits or more are not filtered since their changed files are unknown.
+// Pull request events need WithChangedFilesResolver.
+func OnPaths(patterns ...string) HandlerOption {
+	globs := mustCompileGlobs(patterns, false)
+
+	return func(c *handlerConfig) {
+		f := c.pathFilter()
+		f.includes = append(f.includes, globs...)
+	}
+}
+
+// ExceptPaths ignores changed files matching any of the globs, so the InputFn
+// doesn't run if all of the changed files match; see OnPaths.
+func ExceptPaths(patterns ...string) HandlerOption {
+	globs := mustCompileGlobs(patterns, false)
+
+	return func(c *handlerConfig) {
+		f := c.pathFilter()
+		f.excludes = append(f.excludes, globs...)
+	}
+}
+
+// WithChangedFilesResolver sets how OnPaths and ExceptPaths get the changed
+// files of pull request events, ie GithubChangedFilesResolver.
+func WithChangedFilesResolver(resolver ChangedFilesResolver) HandlerOption {
+	return func(c *handlerConfig) {
+		c.pathFilter().resolver = resolver
+	}
+}
+
+func (c *handlerConfig) pathFilter() *pathFilter {
+	if c.paths == nil {
+		c.paths = &pathFilter{}
+		c.add(c.paths.check)
+	}
+
+	return c.paths
+}
+
+func (f *pathFilter) check(i interface{}) (string, error) {
+	if len(f.includes) == 0 && len(f.excludes) == 0 {
+		return "", nil
+	}
+
+	files, known, err := f.changedFiles(i)
+	if err != nil || !known {
+		return "", err
+	}
+
+	if files == nil {
+		return "event has no changed files", nil
+	}
+
+	for _, file := range files {
+		if len(f.includes) > 0 && !f.includes.Match(file) {
+			continue
+		}
+
+		if !f.excludes.Match(file) {
+			return "", nil
+		}
+	}
+
+	switch {
+	case len(f.excludes) == 0:
+		return fmt.Sprintf("no changed file matches '%s'", f.includes), nil
+	case len(f.includes) == 0:
+		return fmt.Sprintf("all changed files are excluded by '%s'", f.excludes), nil
+	}
+
+	return fmt.Sprintf("no changed file matches '%s' except '%s'", f.includes, f.excludes), nil
+}
+
+// maxPushCommits is the maximum number of commits of webhook push payloads.
+const maxPushCommits = 2048
+
+// changedFiles returns nil if the event has no changed files, and known false
+// if its changed files can't be known from the payload.
+func (f *pathFilter) changedFiles(i interface{}) (files []string, known bool, err error) {
+	switch e := i.(type) {
+	case *github.PushEvent:
+		if len(e.Commits) == 0 {
+			return nil, true, nil
+		}
+
+		if len(e.Commits) >= maxPushCommits {
+			return nil, false, nil
+		}
+
+		files := []string{}
+		for _, commit := range e.Commits {
+			files = append(files, commit.Added...)
+			files = append(files, commit.Modified...)
+			files = append(files, commit.Removed...)
+		}
+		return files, true, nil
+
+	case *github.PullRequestEvent:
+		if f.resolver == nil {
+			return nil, false, fmt.Errorf("ERROR: path options need WithChangedFilesResolver for pull_request events")
+		}
+
+		repo := e.GetRepo()
+		files, err := f.resolver(ContextOf(i), repo.GetOwner().GetLogin(), repo.GetName(), e.GetNumber())
+		if err != nil {
+			return nil, false, err
+		}
+
+		if files == nil {
+			files = []string{}
+		}
+		return files, true, nil
+	}
+
+	return nil, true, nil
+}
@@ -0,0 +1,174 @@
+package ghhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/go-github/github"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPathOptions(t *testing.T) {
+	Convey("Path options", t, func() {
+		Reset(func() { ResetHandlers() })
+
+		var fn InputFn = func(e interface{}) (*Response, error) { return localSuccessResp("ok") }
+
+		push := &events.APIGatewayProxyRequest{
+			Headers: map[string]string{"X-GitHub-Event": "push"},
+			Body: `{
+				"ref": "refs/heads/master",
+				"commits": [
+					{"added": ["api/v1.proto"], "modified": ["README.md"], "removed": []},
+					{"added": [], "modified": ["terraform/main.tf"], "removed": ["docs/old.md"]}
+				]
+			}`,
+		}
+
+		dispatch := func(r *events.APIGatewayProxyRequest, event Event, opts ...HandlerOption) (int, string) {
+			EventHandlerWithOptions(event, fn, opts...)
+
+			resp, err := DefaultHandler(r)
+			So(err, ShouldBeNil)
+			return resp.StatusCode, resp.Body
+		}
+
+		Convey("OnPaths matches push commits", func() {
+			_, body := dispatch(push, PushEvent, OnPaths("terraform/**"))
+			So(body, ShouldEqual, "ok")
+		})
+
+		Convey("OnPaths drops pushes without matching files", func() {
+			_, body := dispatch(push, PushEvent, OnPaths("*.proto"))
+			So(body, ShouldEqual, "Dropping unmatched event for option: no changed file matches '[*.proto]'")
+		})
+
+		pushOf := func(n int, file string) *events.APIGatewayProxyRequest {
+			commits := make([]map[string][]string, n)
+			for i := range commits {
+				commits[i] = map[string][]string{"modified": {file}}
+			}
+			b, _ := json.Marshal(map[string]interface{}{"ref": "refs/heads/master", "commits": commits})
+
+			r := *push
+			r.Body = string(b)
+			return &r
+		}
+
+		Convey("OnPaths drops pushes with no commits", func() {
+			_, body := dispatch(pushOf(0, ""), PushEvent, OnPaths("**"))
+			So(body, ShouldEqual, "Dropping unmatched event for option: event has no changed files")
+		})
+
+		Convey("OnPaths filters pushes of 20 commits", func() {
+			_, body := dispatch(pushOf(20, "README.md"), PushEvent, OnPaths("*.proto"))
+			So(body, ShouldEqual, "Dropping unmatched event for option: no changed file matches '[*.proto]'")
+
+			ResetHandlers()
+			_, body = dispatch(pushOf(20, "api.proto"), PushEvent, OnPaths("*.proto"))
+			So(body, ShouldEqual, "ok")
+		})
+
+		Convey("OnPaths doesn't drop pushes of 2048 commits or more", func() {
+			_, body := dispatch(pushOf(2047, "README.md"), PushEvent, OnPaths("*.proto"))
+			So(body, ShouldEqual, "Dropping unmatched event for option: no changed file matches '[*.proto]'")
+
+			ResetHandlers()
+			_, body = dispatch(pushOf(2048, "README.md"), PushEvent, OnPaths("*.proto"))
+			So(body, ShouldEqual, "ok")
+		})
+
+		Convey("ExceptPaths drops pushes when all files are excluded", func() {
+			_, body := dispatch(push, PushEvent, ExceptPaths("**/*.md", "**/*.proto", "terraform/**"))
+			So(body, ShouldEqual, "Dropping unmatched event for option: all changed files are excluded by '[**/*.md **/*.proto terraform/**]'")
+		})
+
+		Convey("OnPaths and ExceptPaths are evaluated together", func() {
+			_, body := dispatch(push, PushEvent, OnPaths("**/*.md"), ExceptPaths("docs/**", "README.md"))
+			So(body, ShouldEqual, "Dropping unmatched event for option: no changed file matches '[**/*.md]' except '[docs/** README.md]'")
+
+			_, body = dispatch(push, PushEvent, OnPaths("**/*.md"), ExceptPaths("docs/**"))
+			So(body, ShouldEqual, "ok")
+		})
+
+		Convey("It uses the resolver for pull requests", func() {
+			var args string
+			resolver := func(ctx context.Context, owner, repo string, number int) ([]string, error) {
+				args = fmt.Sprintf("%s/%s#%d", owner, repo, number)
+				return []string{"terraform/main.tf"}, nil
+			}
+
+			_, body := dispatch(PullRequestProxyRequest, PullRequestEvent, OnPaths("terraform/**"), WithChangedFilesResolver(resolver))
+			So(body, ShouldEqual, "ok")
+			So(args, ShouldEqual, "baxterthehacker/public-repo#1")
+		})
+
+		Convey("It passes the context of the handler to the resolver", func() {
+			tracer := NewRecordingTracer()
+			DeliveryTracer = tracer
+			Reset(func() { DeliveryTracer = NoopTracer{} })
+
+			resolver := func(ctx context.Context, owner, repo string, number int) ([]string, error) {
+				_, span := DeliveryTracer.Start(ctx, "resolver")
+				span.End()
+				return []string{"terraform/main.tf"}, nil
+			}
+
+			dispatch(PullRequestProxyRequest, PullRequestEvent, OnPaths("terraform/**"), WithChangedFilesResolver(resolver))
+
+			handler := tracer.Find("ghhook.handler")[0]
+			So(tracer.Find("resolver")[0].ParentID, ShouldEqual, handler.ID)
+		})
+
+		Convey("It returns the resolver errors", func() {
+			resolver := func(ctx context.Context, owner, repo string, number int) ([]string, error) {
+				return nil, errors.New("rate limited")
+			}
+
+			status, _ := dispatch(PullRequestProxyRequest, PullRequestEvent, OnPaths("terraform/**"), WithChangedFilesResolver(resolver))
+			So(status, ShouldEqual, 500)
+		})
+
+		Convey("It returns error for pull requests without resolver", func() {
+			status, _ := dispatch(PullRequestProxyRequest, PullRequestEvent, OnPaths("terraform/**"))
+			So(status, ShouldEqual, 500)
+		})
+
+		Convey("It drops events without changed files", func() {
+			_, body := dispatch(CreateEventProxyRequest, CreateEvent, OnPaths("**"))
+			So(body, ShouldEqual, "Dropping unmatched event for option: event has no changed files")
+		})
+	})
+
+	Convey("GithubChangedFilesResolver", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/repos/o/r/pulls/1/files" {
+				http.NotFound(w, r)
+				return
+			}
+
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?per_page=100&page=2>; rel="next"`, r.Host, r.URL.Path))
+				fmt.Fprint(w, `[{"filename": "a.go"}]`)
+				return
+			}
+
+			fmt.Fprint(w, `[{"filename": "b.go"}]`)
+		}))
+		Reset(server.Close)
+
+		client := github.NewClient(nil)
+		client.BaseURL, _ = url.Parse(server.URL + "/")
+
+		files, err := GithubChangedFilesResolver(client)(context.Background(), "o", "r", 1)
+		So(err, ShouldBeNil)
+		So(files, ShouldResemble, []string{"a.go", "b.go"})
+	})
+}
@@ -0,0 +1,71 @@
+package ghhook
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// payload lazily decodes the raw body of a delivery into the generic map used
+// by filters, so it's decoded at most once no matter how many filters run.
+type payload struct {
+	body []byte
+
+	// dropReason is set when the filters of the running InputFn drop the
+	// event.
+	dropReason string
+
+	// ctx is the context of the running InputFn; see ContextOf.
+	ctx context.Context
+
+	once sync.Once
+	m    map[string]interface{}
+	err  error
+}
+
+func (p *payload) Map() (map[string]interface{}, error) {
+	p.once.Do(func() {
+		p.err = json.Unmarshal(p.body, &p.m)
+	})
+
+	return p.m, p.err
+}
+
+// payloads maps the parsed events being dispatched by DefaultHandler to their
+// payload.
+var payloads sync.Map
+
+// withPayload makes the payload available to filters of the parsed event i
+// while fn runs.
+func withPayload(i interface{}, p *payload, fn func() (*Response, error)) (*Response, error) {
+	payloads.Store(i, p)
+	defer payloads.Delete(i)
+
+	return fn()
+}
+
+// eventMap returns the generic map of the parsed event. Events dispatched by
+// DefaultHandler share the map decoded from the raw body, which must not be
+// modified; other events are converted on each call.
+func eventMap(i interface{}) (map[string]interface{}, error) {
+	if p, ok := payloads.Load(i); ok {
+		return p.(*payload).Map()
+	}
+
+	return eventToMap(i)
+}
+
+// eventToMap converts the parsed event into the generic map used by filters.
+func eventToMap(e interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
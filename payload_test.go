@@ -0,0 +1,78 @@
+package ghhook
+
+import (
+	"testing"
+
+	"github.com/google/go-github/github"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEventMap(t *testing.T) {
+	Convey("eventMap", t, func() {
+		Reset(func() { ResetHandlers() })
+
+		Convey("It shares the map decoded from the body with all filters", func() {
+			var maps []map[string]interface{}
+			filterFn := func(m map[string]interface{}) bool {
+				maps = append(maps, m)
+				return true
+			}
+
+			var fn InputFn = func(e interface{}) (*Response, error) { return localSuccessResp("ok") }
+			EventHandlerFunctionFilter(PullRequestEvent, filterFn, fn)
+			EventHandlerFunctionFilter(PullRequestEvent, filterFn, fn)
+
+			_, err := DefaultHandler(PullRequestProxyRequest)
+			So(err, ShouldBeNil)
+			So(len(maps), ShouldEqual, 2)
+			So(maps[0], ShouldEqual, maps[1])
+
+			// fields that are not part of go-github's types are kept.
+			So(maps[0]["pull_request"].(map[string]interface{})["merged_at"], ShouldBeNil)
+			So(maps[0]["pull_request"], ShouldContainKey, "merged_at")
+		})
+
+		Convey("It converts events that are not dispatched by DefaultHandler", func() {
+			e, err := github.ParseWebHook("pull_request", []byte(PullRequestProxyRequest.Body))
+			So(err, ShouldBeNil)
+
+			m, err := eventMap(e)
+			So(err, ShouldBeNil)
+			So(m["action"], ShouldEqual, "opened")
+		})
+	})
+}
+
+func benchmarkFilteredHandlers(b *testing.B, dispatch func()) {
+	defer ResetHandlers()
+
+	var fn InputFn = func(e interface{}) (*Response, error) { return localSuccessResp("ok") }
+	for i := 0; i < 10; i++ {
+		EventHandlerActionFilter(PullRequestEvent, map[string][]string{"action": []string{"opened"}}, fn)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dispatch()
+	}
+}
+
+// BenchmarkFilteredHandlers dispatches a pull_request delivery to ten filtered
+// handlers, decoding the body into the generic map once.
+func BenchmarkFilteredHandlers(b *testing.B) {
+	benchmarkFilteredHandlers(b, func() {
+		DefaultHandler(PullRequestProxyRequest)
+	})
+}
+
+// BenchmarkFilteredHandlersWithoutPayload runs the same handlers on the parsed
+// event only, so each filter converts the event into the generic map.
+func BenchmarkFilteredHandlersWithoutPayload(b *testing.B) {
+	benchmarkFilteredHandlers(b, func() {
+		i, _ := github.ParseWebHook("pull_request", []byte(PullRequestProxyRequest.Body))
+		for _, fn := range Handlers[PullRequestEvent] {
+			fn(i)
+		}
+	})
+}
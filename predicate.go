@@ -0,0 +1,325 @@
+package ghhook
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// Predicate is a composable event filter.
+//
+// Match works on the event decoded into a generic map, so it can be used with
+// EventHandlerFunctionFilter:
+//
+//	ghhook.EventHandlerFunctionFilter(ghhook.PullRequestEvent, p.Match, fn)
+//
+// MatchEvent works on the parsed event, ie *github.PullRequestEvent, and is
+// used by EventHandlerPredicate.
+type Predicate interface {
+	Match(m map[string]interface{}) bool
+	MatchEvent(e interface{}) bool
+
+	// String describes the predicate, ie `(action in ["opened"] && !sender is bot)`.
+	String() string
+}
+
+// EventHandlerPredicate is similar to EventHandler with addition of checking
+// if the parsed event matches the given predicate. Dropped events are
+// responded with the description of the predicate.
+//
+// Example:
+//
+//	ghhook.EventHandlerPredicate(
+//	  ghhook.PullRequestEvent,
+//	  ghhook.And(ghhook.ActionIs("opened"), ghhook.Not(ghhook.SenderIsBot())),
+//	  func(e interface{}) (*ghhook.Response, error) { return nil, nil },
+//	)
+func EventHandlerPredicate(event Event, p Predicate, fn InputFn) {
+	wrappedFn := func(i interface{}) (*Response, error) {
+		reason, _ := traceFilter(i, "predicate", func() (string, error) {
+			if !p.MatchEvent(i) {
+				return fmt.Sprintf("Dropping unmatched event for predicate: %s", p), nil
+			}
+
+			return "", nil
+		})
+		if reason != "" {
+			return dropEvent(i, reason)
+		}
+
+		return fn(i)
+	}
+
+	Handlers[event] = append(Handlers[event], checkSenders(wrappedFn, false))
+}
+
+// mapPredicate is a Predicate that's evaluated on the generic map. MatchEvent
+// looks up the map of the parsed event; see eventMap.
+type mapPredicate struct {
+	desc  string
+	match func(m map[string]interface{}) bool
+}
+
+func (p *mapPredicate) Match(m map[string]interface{}) bool { return p.match(m) }
+func (p *mapPredicate) String() string                      { return p.desc }
+
+func (p *mapPredicate) MatchEvent(e interface{}) bool {
+	m, err := eventMap(e)
+	return err == nil && p.match(m)
+}
+
+// eventPredicate is a Predicate with separate implementations for the generic
+// map and the parsed event.
+type eventPredicate struct {
+	mapPredicate
+	matchEvent func(e interface{}) bool
+}
+
+func (p *eventPredicate) MatchEvent(e interface{}) bool { return p.matchEvent(e) }
+
+// And matches if all of the predicates match.
+func And(ps ...Predicate) Predicate {
+	return &eventPredicate{
+		mapPredicate: mapPredicate{
+			desc: joinPredicates(ps, " && "),
+			match: func(m map[string]interface{}) bool {
+				for _, p := range ps {
+					if !p.Match(m) {
+						return false
+					}
+				}
+				return true
+			},
+		},
+		matchEvent: func(e interface{}) bool {
+			for _, p := range ps {
+				if !p.MatchEvent(e) {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+// Or matches if any of the predicates match.
+func Or(ps ...Predicate) Predicate {
+	return &eventPredicate{
+		mapPredicate: mapPredicate{
+			desc: joinPredicates(ps, " || "),
+			match: func(m map[string]interface{}) bool {
+				for _, p := range ps {
+					if p.Match(m) {
+						return true
+					}
+				}
+				return false
+			},
+		},
+		matchEvent: func(e interface{}) bool {
+			for _, p := range ps {
+				if p.MatchEvent(e) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// Not matches if the predicate doesn't match.
+func Not(p Predicate) Predicate {
+	return &eventPredicate{
+		mapPredicate: mapPredicate{
+			desc:  "!" + p.String(),
+			match: func(m map[string]interface{}) bool { return !p.Match(m) },
+		},
+		matchEvent: func(e interface{}) bool { return !p.MatchEvent(e) },
+	}
+}
+
+// FieldEquals matches if any value at the path is equal to value; see
+// MatchFilters for how non string values are compared. It panics if path is
+// invalid.
+func FieldEquals(path, value string) Predicate {
+	mustParsePath(path)
+
+	return &mapPredicate{
+		desc: fmt.Sprintf("%s == %q", path, value),
+		match: func(m map[string]interface{}) bool {
+			return matchAny(ResolvePath(m, path), []string{value})
+		},
+	}
+}
+
+// FieldIn matches if any value at the path is equal to any of the values. It
+// panics if path is invalid.
+func FieldIn(path string, values ...string) Predicate {
+	mustParsePath(path)
+
+	return &mapPredicate{
+		desc: fmt.Sprintf("%s in %q", path, values),
+		match: func(m map[string]interface{}) bool {
+			return matchAny(ResolvePath(m, path), values)
+		},
+	}
+}
+
+// FieldMatchesRegex matches if any string value at the path matches the
+// regular expression. It panics if path or the expression is invalid.
+func FieldMatchesRegex(path, expr string) Predicate {
+	mustParsePath(path)
+	re := regexp.MustCompile(expr)
+
+	return &mapPredicate{
+		desc: fmt.Sprintf("%s =~ /%s/", path, expr),
+		match: func(m map[string]interface{}) bool {
+			for _, v := range ResolvePath(m, path) {
+				if s, ok := v.(string); ok && re.MatchString(s) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// FieldExists matches if there's a non null value at the path. It panics if
+// path is invalid.
+func FieldExists(path string) Predicate {
+	mustParsePath(path)
+
+	return &mapPredicate{
+		desc: fmt.Sprintf("exists(%s)", path),
+		match: func(m map[string]interface{}) bool {
+			for _, v := range ResolvePath(m, path) {
+				if v != nil {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// RepoIs matches if the repository full name, ie 'WalkerAndCoBrandsInc/ghhook',
+// is any of the given names. Names are compared case insensitively.
+func RepoIs(fullNames ...string) Predicate {
+	matchName := func(name string) bool {
+		for _, fullName := range fullNames {
+			if strings.EqualFold(name, fullName) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return &eventPredicate{
+		mapPredicate: mapPredicate{
+			desc: fmt.Sprintf("repository.full_name in %q", fullNames),
+			match: func(m map[string]interface{}) bool {
+				name, _ := lookupPath(m, "repository.full_name").(string)
+				return matchName(name)
+			},
+		},
+		matchEvent: func(e interface{}) bool {
+			return matchName(eventRepoFullName(e))
+		},
+	}
+}
+
+// ActionIs matches if the action of the event is any of the given actions.
+func ActionIs(actions ...string) Predicate {
+	matchAction := func(action string) bool {
+		for _, a := range actions {
+			if action == a {
+				return true
+			}
+		}
+		return false
+	}
+
+	return &eventPredicate{
+		mapPredicate: mapPredicate{
+			desc: fmt.Sprintf("action in %q", actions),
+			match: func(m map[string]interface{}) bool {
+				action, _ := m["action"].(string)
+				return matchAction(action)
+			},
+		},
+		matchEvent: func(e interface{}) bool {
+			action, _ := eventAction(e)
+			return matchAction(action)
+		},
+	}
+}
+
+// SenderIsBot matches if the sender of the event is a bot account, ie a Github
+// App.
+func SenderIsBot() Predicate {
+	return &eventPredicate{
+		mapPredicate: mapPredicate{
+			desc: "sender is bot",
+			match: func(m map[string]interface{}) bool {
+				userType, _ := lookupPath(m, "sender.type").(string)
+				login, _ := lookupPath(m, "sender.login").(string)
+				return isBot(userType, login)
+			},
+		},
+		matchEvent: func(e interface{}) bool {
+			sender := eventSender(e)
+			return sender != nil && isBot(sender.GetType(), sender.GetLogin())
+		},
+	}
+}
+
+func isBot(userType, login string) bool {
+	return userType == "Bot" || strings.HasSuffix(login, "[bot]")
+}
+
+// eventRepoFullName returns the repository full name of the parsed event.
+func eventRepoFullName(e interface{}) string {
+	switch e := e.(type) {
+	case *github.PushEvent:
+		return e.GetRepo().GetFullName()
+	case interface{ GetRepo() *github.Repository }:
+		return e.GetRepo().GetFullName()
+	}
+
+	return ""
+}
+
+// eventAction returns the action of the parsed event, if it has one.
+func eventAction(e interface{}) (string, bool) {
+	if e, ok := e.(interface{ GetAction() string }); ok {
+		return e.GetAction(), true
+	}
+
+	return "", false
+}
+
+// eventSender returns the sender of the parsed event, if it has one.
+func eventSender(e interface{}) *github.User {
+	if e, ok := e.(interface{ GetSender() *github.User }); ok {
+		return e.GetSender()
+	}
+
+	return nil
+}
+
+func joinPredicates(ps []Predicate, sep string) string {
+	descs := make([]string, len(ps))
+	for i, p := range ps {
+		descs[i] = p.String()
+	}
+
+	return "(" + strings.Join(descs, sep) + ")"
+}
+
+func mustParsePath(path string) {
+	if err := ParsePath(path); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,81 @@
+package ghhook
+
+import (
+	"testing"
+
+	"github.com/google/go-github/github"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPredicates(t *testing.T) {
+	Convey("Predicates", t, func() {
+		e, err := github.ParseWebHook("pull_request", []byte(PullRequestProxyRequest.Body))
+		So(err, ShouldBeNil)
+
+		m, err := eventToMap(e)
+		So(err, ShouldBeNil)
+
+		matches := func(p Predicate) bool {
+			So(p.Match(m), ShouldEqual, p.MatchEvent(e))
+			return p.Match(m)
+		}
+
+		Convey("Field predicates", func() {
+			So(matches(FieldEquals("pull_request.base.ref", "master")), ShouldBeTrue)
+			So(matches(FieldEquals("number", "1")), ShouldBeTrue)
+			So(matches(FieldIn("action", "closed", "opened")), ShouldBeTrue)
+			So(matches(FieldIn("action", "closed")), ShouldBeFalse)
+			So(matches(FieldMatchesRegex("pull_request.head.ref", "^change")), ShouldBeTrue)
+			So(matches(FieldExists("pull_request.merged_at")), ShouldBeFalse)
+			So(matches(FieldExists("pull_request.title")), ShouldBeTrue)
+		})
+
+		Convey("Event predicates", func() {
+			So(matches(RepoIs("BaxterTheHacker/public-repo")), ShouldBeTrue)
+			So(matches(RepoIs("baxterthehacker/private-repo")), ShouldBeFalse)
+			So(matches(ActionIs("opened")), ShouldBeTrue)
+			So(matches(SenderIsBot()), ShouldBeFalse)
+		})
+
+		Convey("Combinators", func() {
+			So(matches(And(ActionIs("opened"), Not(SenderIsBot()))), ShouldBeTrue)
+			So(matches(And(ActionIs("opened"), SenderIsBot())), ShouldBeFalse)
+			So(matches(Or(ActionIs("closed"), FieldEquals("number", "1"))), ShouldBeTrue)
+			So(matches(Or()), ShouldBeFalse)
+			So(matches(And()), ShouldBeTrue)
+		})
+
+		Convey("String describes the predicate", func() {
+			p := And(ActionIs("opened", "reopened"), Not(SenderIsBot()), Or(FieldEquals("pull_request.base.ref", "master"), FieldExists("label")))
+			So(p.String(), ShouldEqual, `(action in ["opened" "reopened"] && !sender is bot && (pull_request.base.ref == "master" || exists(label)))`)
+		})
+
+		Convey("It panics on invalid paths", func() {
+			So(func() { FieldEquals("a..b", "") }, ShouldPanic)
+		})
+	})
+
+	Convey("EventHandlerPredicate", t, func() {
+		Reset(func() { ResetHandlers() })
+
+		Convey("It drops unmatched events with the predicate description", func() {
+			EventHandlerPredicate(PullRequestEvent, ActionIs("closed"), func(e interface{}) (*Response, error) {
+				return localSuccessResp("ok")
+			})
+
+			resp, err := DefaultHandler(PullRequestProxyRequest)
+			So(err, ShouldBeNil)
+			So(resp.Body, ShouldEqual, `Dropping unmatched event for predicate: action in ["closed"]`)
+		})
+
+		Convey("It works with EventHandlerFunctionFilter", func() {
+			EventHandlerFunctionFilter(PullRequestEvent, ActionIs("opened").Match, func(e interface{}) (*Response, error) {
+				return localSuccessResp("ok")
+			})
+
+			resp, err := DefaultHandler(PullRequestProxyRequest)
+			So(err, ShouldBeNil)
+			So(resp.Body, ShouldEqual, "ok")
+		})
+	})
+}
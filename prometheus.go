@@ -0,0 +1,192 @@
+package ghhook
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPrometheusBuckets are the upper bounds, in seconds, of the duration
+// histograms of PrometheusSink.
+var DefaultPrometheusBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// PrometheusSink is a MetricsSink that aggregates metrics in memory and serves
+// them in the Prometheus text exposition format, ie on /metrics when ghhook
+// runs behind HTTPHandler.
+//
+// Counters are exposed as '<namespace>_<name>_total', ie
+// ghhook_deliveries_received_total, and durations as histograms in seconds, ie
+// ghhook_delivery_duration_seconds. Dimensions are exposed as labels.
+type PrometheusSink struct {
+	Namespace string
+	Buckets   []float64
+
+	mu       sync.Mutex
+	families map[string]*promFamily
+}
+
+type promFamily struct {
+	kind   string
+	series map[string]*promSeries
+}
+
+type promSeries struct {
+	value   float64
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+// NewPrometheusSink returns a PrometheusSink with DefaultPrometheusBuckets.
+func NewPrometheusSink(namespace string) *PrometheusSink {
+	return &PrometheusSink{Namespace: namespace, Buckets: DefaultPrometheusBuckets}
+}
+
+func (s *PrometheusSink) Emit(metrics []Metric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.families == nil {
+		s.families = map[string]*promFamily{}
+	}
+
+	for _, m := range metrics {
+		labels := promLabels(m.Dimensions)
+
+		switch m.Unit {
+		case UnitMilliseconds:
+			seconds := m.Value * float64(time.Millisecond) / float64(time.Second)
+
+			series := s.series(s.metricName(m.Name, "seconds"), "histogram", labels)
+			if series.buckets == nil {
+				series.buckets = make([]uint64, len(s.Buckets))
+			}
+			for i, le := range s.Buckets {
+				if seconds <= le {
+					series.buckets[i]++
+				}
+			}
+			series.sum += seconds
+			series.count++
+		default:
+			s.series(s.metricName(m.Name, "total"), "counter", labels).value += m.Value
+		}
+	}
+}
+
+func (s *PrometheusSink) series(name, kind, labels string) *promSeries {
+	family, ok := s.families[name]
+	if !ok {
+		family = &promFamily{kind: kind, series: map[string]*promSeries{}}
+		s.families[name] = family
+	}
+
+	series, ok := family.series[labels]
+	if !ok {
+		series = &promSeries{}
+		family.series[labels] = series
+	}
+
+	return series
+}
+
+func (s *PrometheusSink) metricName(name, suffix string) string {
+	if s.Namespace != "" {
+		name = s.Namespace + "_" + name
+	}
+
+	return name + "_" + suffix
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (s *PrometheusSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.WriteMetrics(w)
+}
+
+// WriteMetrics writes the metrics in the Prometheus text exposition format,
+// sorted by name and labels.
+func (s *PrometheusSink) WriteMetrics(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+
+	names := make([]string, 0, len(s.families))
+	for name := range s.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		family := s.families[name]
+		fmt.Fprintf(bw, "# TYPE %s %s\n", name, family.kind)
+
+		labelSets := make([]string, 0, len(family.series))
+		for labels := range family.series {
+			labelSets = append(labelSets, labels)
+		}
+		sort.Strings(labelSets)
+
+		for _, labels := range labelSets {
+			series := family.series[labels]
+
+			if family.kind == "counter" {
+				fmt.Fprintf(bw, "%s%s %s\n", name, wrapLabels(labels), formatFloat(series.value))
+				continue
+			}
+
+			for i, le := range s.Buckets {
+				fmt.Fprintf(bw, "%s_bucket%s %d\n", name, wrapLabels(joinLabels(labels, `le="`+formatFloat(le)+`"`)), series.buckets[i])
+			}
+			fmt.Fprintf(bw, "%s_bucket%s %d\n", name, wrapLabels(joinLabels(labels, `le="+Inf"`)), series.count)
+			fmt.Fprintf(bw, "%s_sum%s %s\n", name, wrapLabels(labels), formatFloat(series.sum))
+			fmt.Fprintf(bw, "%s_count%s %d\n", name, wrapLabels(labels), series.count)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// promLabels renders the dimensions as labels sorted by name, without braces.
+func promLabels(dims map[string]string) string {
+	pairs := make([]string, 0, len(dims))
+	for k, v := range dims {
+		pairs = append(pairs, k+`="`+escapeLabelValue(v)+`"`)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueReplacer.Replace(v)
+}
+
+func joinLabels(labels, label string) string {
+	if labels == "" {
+		return label
+	}
+
+	return labels + "," + label
+}
+
+func wrapLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+
+	return "{" + labels + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
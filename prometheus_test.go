@@ -0,0 +1,59 @@
+package ghhook
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPrometheusSink(t *testing.T) {
+	Convey("PrometheusSink", t, func() {
+		sink := NewPrometheusSink("ghhook")
+		sink.Buckets = []float64{0.01, 0.1}
+
+		dims := map[string]string{"event": "push", "action": "none", "repository": `a/"b"`}
+		for _, ms := range []float64{5, 50} {
+			sink.Emit([]Metric{
+				{Name: "deliveries_received", Unit: UnitCount, Value: 1, Dimensions: dims},
+				{Name: "delivery_duration", Unit: UnitMilliseconds, Value: ms, Dimensions: dims},
+			})
+		}
+
+		Convey("It writes the text exposition format", func() {
+			var buf bytes.Buffer
+			So(sink.WriteMetrics(&buf), ShouldBeNil)
+
+			So(buf.String(), ShouldEqual, `# TYPE ghhook_deliveries_received_total counter
+ghhook_deliveries_received_total{action="none",event="push",repository="a/\"b\""} 2
+# TYPE ghhook_delivery_duration_seconds histogram
+ghhook_delivery_duration_seconds_bucket{action="none",event="push",repository="a/\"b\"",le="0.01"} 1
+ghhook_delivery_duration_seconds_bucket{action="none",event="push",repository="a/\"b\"",le="0.1"} 2
+ghhook_delivery_duration_seconds_bucket{action="none",event="push",repository="a/\"b\"",le="+Inf"} 2
+ghhook_delivery_duration_seconds_sum{action="none",event="push",repository="a/\"b\""} 0.055
+ghhook_delivery_duration_seconds_count{action="none",event="push",repository="a/\"b\""} 2
+`)
+		})
+
+		Convey("It serves the metrics", func() {
+			w := httptest.NewRecorder()
+			sink.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+			So(w.Code, ShouldEqual, 200)
+			So(w.Header().Get("Content-Type"), ShouldStartWith, "text/plain; version=0.0.4")
+			So(w.Body.String(), ShouldContainSubstring, "ghhook_deliveries_received_total")
+		})
+
+		Convey("It receives the metrics of DefaultHandler", func() {
+			Metrics = sink
+			Reset(func() { Metrics = nil })
+
+			DefaultHandler(CreateEventProxyRequest)
+
+			var buf bytes.Buffer
+			sink.WriteMetrics(&buf)
+			So(buf.String(), ShouldContainSubstring, `ghhook_deliveries_dropped_unregistered_total{action="none",event="create",repository="baxterthehacker/public-repo"} 1`)
+		})
+	})
+}
@@ -0,0 +1,185 @@
+package ghhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Outcome is how DefaultHandler handled a delivery.
+type Outcome string
+
+const (
+	// OutcomeSucceeded is a delivery for which at least one InputFn ran
+	// successfully.
+	OutcomeSucceeded Outcome = "succeeded"
+
+	// OutcomeFailed is a delivery for which an InputFn or ghhook failed.
+	OutcomeFailed Outcome = "failed"
+
+	// OutcomeRejected is a delivery rejected before any InputFn ran, ie for its
+	// source ip or as a replay.
+	OutcomeRejected Outcome = "rejected"
+
+	// OutcomeDroppedUnregistered is a delivery of an event with no InputFn.
+	OutcomeDroppedUnregistered Outcome = "dropped_unregistered"
+
+	// OutcomeDroppedFiltered is a delivery dropped by the filters of all of its
+	// InputFn.
+	OutcomeDroppedFiltered Outcome = "dropped_filtered"
+
+	// OutcomeDuplicate is a delivery that was already processed; see
+	// Deliveries.
+	OutcomeDuplicate Outcome = "duplicate"
+)
+
+// HandlerOutcome is how a single InputFn handled a delivery.
+type HandlerOutcome string
+
+const (
+	HandlerSucceeded HandlerOutcome = "succeeded"
+	HandlerFailed    HandlerOutcome = "failed"
+	HandlerDropped   HandlerOutcome = "dropped"
+)
+
+// HandlerResult is the result of running a single InputFn.
+type HandlerResult struct {
+	// Index is the index of the InputFn in Handlers.
+	Index    int            `json:"index"`
+	Outcome  HandlerOutcome `json:"outcome"`
+	Reason   string         `json:"reason,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Duration time.Duration  `json:"duration"`
+
+	// Response is the response of the InputFn, unless it failed.
+	Response *Response `json:"-"`
+}
+
+// DeliveryRecord describes a delivery handled by DefaultHandler. It's passed
+// to DeliveryLogger, Metrics and AuditLog once the delivery is handled.
+// Deliveries dispatched by ReplayDelivery are only passed to DeliveryLogger.
+type DeliveryRecord struct {
+	DeliveryID string
+	Event      Event
+	Action     string
+	Repository string
+	Sender     string
+	Signature  SignatureStatus
+
+	Handlers   []HandlerResult
+	Outcome    Outcome
+	StatusCode int
+	Error      string
+
+	// AuditError is the error of AuditLog, which is called before
+	// DeliveryLogger so the records it fails to keep are logged.
+	AuditError string
+
+	Start    time.Time
+	Duration time.Duration
+
+	// Replay is set for deliveries dispatched by ReplayDelivery.
+	Replay bool
+
+	headers map[string]string
+	payload *payload
+
+	// ctx carries span, the 'ghhook.delivery' span of DeliveryTracer.
+	ctx  context.Context
+	span Span
+}
+
+func newDeliveryRecord(r *events.APIGatewayProxyRequest) *DeliveryRecord {
+	rec := &DeliveryRecord{
+		Start:     now(),
+		Signature: SignatureUnchecked,
+		headers:   r.Headers,
+		payload:   &payload{body: []byte(r.Body)},
+	}
+	rec.ctx, rec.span = startSpan(context.Background(), "ghhook.delivery")
+	rec.payload.ctx = rec.ctx
+
+	rec.DeliveryID, _ = headerValue(r.Headers, "X-GitHub-Delivery")
+	if eventName, ok := headerValue(r.Headers, "X-GitHub-Event"); ok {
+		rec.Event = Event(eventName)
+	}
+
+	return rec
+}
+
+// fail records the error and returns its response.
+func (rec *DeliveryRecord) fail(outcome Outcome, err error) (*events.APIGatewayProxyResponse, error) {
+	rec.Outcome = outcome
+	rec.Error = err.Error()
+
+	return ErrorResponseFn(err)
+}
+
+// recordReleaseError records an error releasing the claim of a failed
+// delivery.
+func (rec *DeliveryRecord) recordReleaseError(err error) {
+	if err != nil {
+		rec.Error = fmt.Sprintf("%s; releasing delivery: %v", rec.Error, err)
+	}
+}
+
+// finish records the response and the fields of the payload, passes the
+// record to AuditLog, DeliveryLogger and Metrics, skipping AuditLog and Metrics
+// for replays, and ends the span of the delivery.
+func (rec *DeliveryRecord) finish(resp *events.APIGatewayProxyResponse) {
+	rec.Duration = now().Sub(rec.Start)
+	if resp != nil {
+		rec.StatusCode = resp.StatusCode
+	}
+
+	defer rec.span.End()
+
+	if DeliveryLogger == nil && Metrics == nil && AuditLog == nil && !tracing() && !rec.Replay {
+		return
+	}
+
+	rec.describe()
+
+	rec.span.SetAttribute("github.delivery_id", rec.DeliveryID)
+	rec.span.SetAttribute("github.event", string(rec.Event))
+	rec.span.SetAttribute("github.action", rec.Action)
+	rec.span.SetAttribute("github.repository", rec.Repository)
+	rec.span.SetAttribute("ghhook.outcome", string(rec.Outcome))
+	rec.span.SetAttribute("http.status_code", rec.StatusCode)
+	if rec.Error != "" {
+		rec.span.RecordError(errors.New(rec.Error))
+	}
+
+	// replays were already counted and audited when they were received.
+	if !rec.Replay && AuditLog != nil {
+		if err := AuditLog.Audit(newAuditRecord(rec)); err != nil {
+			rec.AuditError = err.Error()
+			rec.span.RecordError(fmt.Errorf("auditing delivery: %v", err))
+		}
+	}
+
+	if DeliveryLogger != nil {
+		DeliveryLogger.LogDelivery(rec)
+	}
+
+	if !rec.Replay && Metrics != nil {
+		Metrics.Emit(deliveryMetrics(rec))
+	}
+}
+
+// describe records the fields of the payload. The payload is only decoded if
+// it wasn't already by the filters.
+func (rec *DeliveryRecord) describe() {
+	if m, err := rec.payload.Map(); err == nil {
+		rec.Action, _ = m["action"].(string)
+		rec.Repository, _ = lookupPath(m, "repository.full_name").(string)
+		rec.Sender, _ = lookupPath(m, "sender.login").(string)
+	}
+}
+
+func durationMS(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
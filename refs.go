@@ -0,0 +1,117 @@
+package ghhook
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RefType is the type of the ref of push, create and delete events.
+type RefType string
+
+const (
+	RefTypeBranch     RefType = "branch"
+	RefTypeTag        RefType = "tag"
+	RefTypeRepository RefType = "repository"
+)
+
+const (
+	branchRefPrefix = "refs/heads/"
+	tagRefPrefix    = "refs/tags/"
+)
+
+// OnBranches runs the InputFn only for push, create and delete events of
+// branches whose name, ie 'master' or 'release/1.0', matches any of the globs.
+// '*' doesn't match '/' while '**' does. It panics if any of the globs is
+// invalid.
+func OnBranches(patterns ...string) HandlerOption {
+	return refOption(RefTypeBranch, mustCompileGlobs(patterns, false), false)
+}
+
+// ExceptBranches doesn't run the InputFn for events of branches whose name
+// matches any of the globs; see OnBranches. Events of other refs are not
+// dropped.
+func ExceptBranches(patterns ...string) HandlerOption {
+	return refOption(RefTypeBranch, mustCompileGlobs(patterns, false), true)
+}
+
+// OnTags runs the InputFn only for push, create and delete events of tags
+// whose name, ie 'v1.0.0', matches any of the globs; see OnBranches.
+func OnTags(patterns ...string) HandlerOption {
+	return refOption(RefTypeTag, mustCompileGlobs(patterns, false), false)
+}
+
+// ExceptTags doesn't run the InputFn for events of tags whose name matches any
+// of the globs; see OnTags.
+func ExceptTags(patterns ...string) HandlerOption {
+	return refOption(RefTypeTag, mustCompileGlobs(patterns, false), true)
+}
+
+// WithRefTypes runs the InputFn only for push, create and delete events of the
+// given ref types.
+func WithRefTypes(refTypes ...RefType) HandlerOption {
+	return func(c *handlerConfig) {
+		c.add(mapCondition(func(m map[string]interface{}) string {
+			refType, _, ok := eventRef(m)
+			if !ok {
+				return "event has no ref"
+			}
+
+			for _, t := range refTypes {
+				if t == refType {
+					return ""
+				}
+			}
+
+			return fmt.Sprintf("ref type '%s' is not in '%v'", refType, refTypes)
+		}))
+	}
+}
+
+func refOption(refType RefType, globs globs, except bool) HandlerOption {
+	return func(c *handlerConfig) {
+		c.add(mapCondition(func(m map[string]interface{}) string {
+			t, name, ok := eventRef(m)
+			matched := ok && t == refType && globs.Match(name)
+
+			switch {
+			case except && matched:
+				return fmt.Sprintf("%s '%s' is excluded by '%s'", refType, name, globs)
+			case except:
+				return ""
+			case !ok:
+				return "event has no ref"
+			case t != refType:
+				return fmt.Sprintf("ref '%s' is not a %s", name, refType)
+			case !matched:
+				return fmt.Sprintf("%s '%s' doesn't match '%s'", refType, name, globs)
+			}
+
+			return ""
+		}))
+	}
+}
+
+// eventRef returns the type and short name of the ref of the event. Push
+// events have full refs, ie 'refs/heads/master', while create and delete
+// events have the short name and a 'ref_type'. Create events of repositories
+// have a null ref.
+func eventRef(m map[string]interface{}) (RefType, string, bool) {
+	ref, hasRef := m["ref"].(string)
+
+	if refType, ok := m["ref_type"].(string); ok {
+		return RefType(refType), ref, true
+	}
+
+	if !hasRef {
+		return "", "", false
+	}
+
+	switch {
+	case strings.HasPrefix(ref, branchRefPrefix):
+		return RefTypeBranch, strings.TrimPrefix(ref, branchRefPrefix), true
+	case strings.HasPrefix(ref, tagRefPrefix):
+		return RefTypeTag, strings.TrimPrefix(ref, tagRefPrefix), true
+	}
+
+	return "", ref, false
+}
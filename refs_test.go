@@ -0,0 +1,108 @@
+package ghhook
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRefOptions(t *testing.T) {
+	Convey("Ref options", t, func() {
+		Reset(func() { ResetHandlers() })
+
+		var fn InputFn = func(e interface{}) (*Response, error) { return localSuccessResp("ok") }
+
+		push := func(ref string) *events.APIGatewayProxyRequest {
+			return &events.APIGatewayProxyRequest{
+				Headers: map[string]string{"X-GitHub-Event": "push"},
+				Body:    `{"ref": "` + ref + `", "repository": {"full_name": "baxterthehacker/public-repo"}}`,
+			}
+		}
+
+		dispatch := func(r *events.APIGatewayProxyRequest, event Event, opts ...HandlerOption) string {
+			EventHandlerWithOptions(event, fn, opts...)
+
+			resp, err := DefaultHandler(r)
+			So(err, ShouldBeNil)
+			return resp.Body
+		}
+
+		Convey("OnBranches matches push refs", func() {
+			So(dispatch(push("refs/heads/master"), PushEvent, OnBranches("master", "release/*")), ShouldEqual, "ok")
+			So(dispatch(push("refs/heads/release/1.0"), PushEvent, OnBranches("master", "release/*")), ShouldEqual, "ok")
+		})
+
+		Convey("OnBranches drops other branches", func() {
+			So(dispatch(push("refs/heads/release/1.0/hotfix"), PushEvent, OnBranches("release/*")), ShouldEqual,
+				"Dropping unmatched event for option: branch 'release/1.0/hotfix' doesn't match '[release/*]'")
+		})
+
+		Convey("OnBranches drops tags", func() {
+			So(dispatch(push("refs/tags/master"), PushEvent, OnBranches("master")), ShouldEqual,
+				"Dropping unmatched event for option: ref 'master' is not a branch")
+		})
+
+		Convey("OnBranches supports '**'", func() {
+			So(dispatch(push("refs/heads/release/1.0/hotfix"), PushEvent, OnBranches("release/**")), ShouldEqual, "ok")
+		})
+
+		Convey("OnTags matches create events", func() {
+			So(dispatch(CreateEventProxyRequest, CreateEvent, OnTags("0.*")), ShouldEqual, "ok")
+			So(dispatch(CreateEventProxyRequest, CreateEvent, OnBranches("*")), ShouldEqual,
+				"Dropping unmatched event for option: ref '0.0.1' is not a branch")
+		})
+
+		Convey("ExceptBranches and ExceptTags", func() {
+			So(dispatch(push("refs/heads/gh-pages"), PushEvent, ExceptBranches("gh-pages")), ShouldEqual,
+				"Dropping unmatched event for option: branch 'gh-pages' is excluded by '[gh-pages]'")
+			So(dispatch(CreateEventProxyRequest, CreateEvent, ExceptTags("*-rc*")), ShouldEqual, "ok")
+		})
+
+		Convey("WithRefTypes", func() {
+			So(dispatch(CreateEventProxyRequest, CreateEvent, WithRefTypes(RefTypeTag)), ShouldEqual, "ok")
+			So(dispatch(push("refs/heads/master"), PushEvent, WithRefTypes(RefTypeTag, RefTypeRepository)), ShouldEqual,
+				"Dropping unmatched event for option: ref type 'branch' is not in '[tag repository]'")
+		})
+
+		Convey("WithRefTypes matches repository create events", func() {
+			r := &events.APIGatewayProxyRequest{
+				Headers: map[string]string{"X-GitHub-Event": "create"},
+				Body:    `{"ref": null, "ref_type": "repository", "master_branch": "master", "repository": {"full_name": "baxterthehacker/public-repo"}}`,
+			}
+
+			So(dispatch(r, CreateEvent, WithRefTypes(RefTypeRepository)), ShouldEqual, "ok")
+			So(dispatch(r, CreateEvent, OnBranches("*")), ShouldEqual,
+				"Dropping unmatched event for option: ref '' is not a branch")
+		})
+
+		Convey("It drops events without ref", func() {
+			So(dispatch(PullRequestProxyRequest, PullRequestEvent, OnBranches("master")), ShouldEqual,
+				"Dropping unmatched event for option: event has no ref")
+		})
+	})
+}
+
+func TestGlob(t *testing.T) {
+	Convey("compileGlob", t, func() {
+		match := func(pattern, name string) bool {
+			g, err := compileGlob(pattern, false)
+			So(err, ShouldBeNil)
+			return g.Match(name)
+		}
+
+		So(match("*.proto", "api.proto"), ShouldBeTrue)
+		So(match("*.proto", "api/v1.proto"), ShouldBeFalse)
+		So(match("**/*.proto", "api.proto"), ShouldBeTrue)
+		So(match("**/*.proto", "api/v1/v1.proto"), ShouldBeTrue)
+		So(match("terraform/**", "terraform/main.tf"), ShouldBeTrue)
+		So(match("terraform/**", "terraform"), ShouldBeFalse)
+		So(match("v1.?", "v1.2"), ShouldBeTrue)
+		So(match("v1.?", "v1x2"), ShouldBeFalse)
+		So(match("v[0-9]", "v3"), ShouldBeTrue)
+		So(match("v[!0-9]", "v3"), ShouldBeFalse)
+
+		_, err := compileGlob("v[0-9", false)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,158 @@
+package ghhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ReplayGuard rejects deliveries that look like replays of a captured
+// delivery: ones whose delivery id was already seen within Window, and ones
+// whose payload timestamps are older than MaxAge.
+type ReplayGuard struct {
+	// Store remembers delivery ids for Window. It should not be the same store
+	// as Deliveries.
+	Store  DeliveryStore
+	Window time.Duration
+
+	// MaxAge is the maximum age of the newest timestamp found at the
+	// TimestampPaths of the event. The age is not checked when it's 0, for
+	// events with no TimestampPaths or when the payload has none of the
+	// timestamps.
+	MaxAge         time.Duration
+	TimestampPaths map[string][]string
+}
+
+// ReplayError is returned by ReplayGuard when it rejects a delivery.
+type ReplayError struct {
+	Reason string
+}
+
+func (e *ReplayError) Error() string {
+	return fmt.Sprintf("ERROR: rejected replayed delivery: %s", e.Reason)
+}
+
+// StatusCode returns the status code of the response for the error.
+func (e *ReplayError) StatusCode() int {
+	return http.StatusForbidden
+}
+
+var (
+	// ReplayProtection is used by DefaultHandler to reject replayed deliveries.
+	// It's disabled when nil.
+	ReplayProtection *ReplayGuard
+
+	// DefaultReplayTimestampPaths are the timestamps of the primary objects of
+	// webhook payloads that are set by the action that triggered the delivery,
+	// keyed by event name, or by 'event.action' for the timestamps only set by
+	// some actions. A nil entry for an action disables the check of its event.
+	// Push events have the time of the push as 'repository.pushed_at', a unix
+	// timestamp. Other events, ie watch or status, have the time of the last
+	// push of the repository there, so their age is not checked.
+	DefaultReplayTimestampPaths = map[string][]string{
+		"deployment":                          {"deployment.updated_at"},
+		"deployment_status":                   {"deployment_status.updated_at"},
+		"issue_comment":                       {"comment.updated_at"},
+		"issue_comment.deleted":               nil,
+		"issues":                              {"issue.updated_at"},
+		"issues.deleted":                      nil,
+		"milestone":                           {"milestone.updated_at"},
+		"milestone.deleted":                   nil,
+		"project":                             {"project.updated_at"},
+		"project.deleted":                     nil,
+		"project_card":                        {"project_card.updated_at"},
+		"project_card.deleted":                nil,
+		"project_column":                      {"project_column.updated_at"},
+		"project_column.deleted":              nil,
+		"pull_request":                        {"pull_request.updated_at"},
+		"push":                                {"repository.pushed_at"},
+		"pull_request_review.submitted":       {"review.submitted_at"},
+		"pull_request_review_comment":         {"comment.updated_at"},
+		"pull_request_review_comment.deleted": nil,
+		"release.published":                   {"release.published_at"},
+	}
+)
+
+// NewReplayGuard returns a ReplayGuard that remembers delivery ids in memory
+// for window and rejects payloads older than maxAge.
+func NewReplayGuard(window, maxAge time.Duration) *ReplayGuard {
+	return &ReplayGuard{
+		Store:          NewMemoryDeliveryStore(10000),
+		Window:         window,
+		MaxAge:         maxAge,
+		TimestampPaths: DefaultReplayTimestampPaths,
+	}
+}
+
+// Check returns a *ReplayError if the delivery should be rejected. Otherwise
+// the delivery id is claimed for Window, until it's released by Release.
+func (g *ReplayGuard) Check(deliveryID string, event Event, body string) error {
+	if deliveryID == "" {
+		return &ReplayError{Reason: "no 'X-GitHub-Delivery' header"}
+	}
+
+	if g.MaxAge > 0 {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &m); err != nil {
+			return &ErrBadRequest{Reason: err.Error()}
+		}
+
+		if t, ok := newestTimestamp(m, g.timestampPaths(event, m)); ok && now().Sub(t) > g.MaxAge {
+			return &ReplayError{Reason: fmt.Sprintf("payload timestamp '%s' is older than %s", t.UTC().Format(time.RFC3339), g.MaxAge)}
+		}
+	}
+
+	claimed, err := g.Store.Claim(deliveryID, g.Window)
+	if err != nil {
+		return err
+	}
+
+	if !claimed {
+		return &ReplayError{Reason: fmt.Sprintf("delivery '%s' was already seen", deliveryID)}
+	}
+
+	return nil
+}
+
+// Release forgets the delivery id, so a delivery that failed after it was
+// checked can be redelivered.
+func (g *ReplayGuard) Release(deliveryID string) error {
+	return g.Store.Release(deliveryID)
+}
+
+func (g *ReplayGuard) timestampPaths(event Event, m map[string]interface{}) []string {
+	if action, ok := m["action"].(string); ok {
+		if paths, ok := g.TimestampPaths[string(event)+"."+action]; ok {
+			return paths
+		}
+	}
+
+	return g.TimestampPaths[string(event)]
+}
+
+func newestTimestamp(m map[string]interface{}, paths []string) (time.Time, bool) {
+	var newest time.Time
+	var found bool
+
+	for _, path := range paths {
+		t, ok := parseTimestamp(lookupPath(m, path))
+		if ok && (!found || t.After(newest)) {
+			newest, found = t, true
+		}
+	}
+
+	return newest, found
+}
+
+func parseTimestamp(v interface{}) (time.Time, bool) {
+	switch v := v.(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		return t, err == nil
+	}
+
+	return time.Time{}, false
+}
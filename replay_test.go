@@ -0,0 +1,154 @@
+package ghhook
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReplayGuard(t *testing.T) {
+	Convey("ReplayProtection", t, func() {
+		// PullRequestProxyRequest was last updated at 2015-05-05T23:40:27Z.
+		current := time.Date(2015, 5, 5, 23, 45, 0, 0, time.UTC)
+		now = func() time.Time { return current }
+		ReplayProtection = NewReplayGuard(time.Hour, 10*time.Minute)
+
+		Reset(func() {
+			now = time.Now
+			ReplayProtection = nil
+			ResetHandlers()
+		})
+
+		EventHandler(PullRequestEvent, func(e interface{}) (*Response, error) {
+			return localSuccessResp("ok")
+		})
+
+		r := &events.APIGatewayProxyRequest{
+			Headers: map[string]string{
+				"X-GitHub-Event":    "pull_request",
+				"X-GitHub-Delivery": "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+			},
+			Body: PullRequestProxyRequest.Body,
+		}
+
+		Convey("It accepts fresh deliveries", func() {
+			resp, err := DefaultHandler(r)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 200)
+			So(resp.Body, ShouldEqual, "ok")
+		})
+
+		Convey("It rejects deliveries seen within the window", func() {
+			DefaultHandler(r)
+
+			resp, err := DefaultHandler(r)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 403)
+			So(resp.Body, ShouldContainSubstring, "was already seen")
+		})
+
+		Convey("It rejects deliveries older than max age", func() {
+			current = current.Add(time.Hour)
+
+			resp, err := DefaultHandler(r)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 403)
+			So(resp.Body, ShouldContainSubstring, "payload timestamp '2015-05-05T23:40:27Z' is older than 10m0s")
+		})
+
+		Convey("It checks the age of pushes with their unix pushed_at", func() {
+			EventHandler(PushEvent, func(e interface{}) (*Response, error) {
+				return localSuccessResp("ok")
+			})
+
+			pushedAt := current.Add(-time.Minute).Unix()
+			push := func(id string) (*events.APIGatewayProxyResponse, error) {
+				return DefaultHandler(&events.APIGatewayProxyRequest{
+					Headers: map[string]string{
+						"X-GitHub-Event":    "push",
+						"X-GitHub-Delivery": id,
+					},
+					Body: fmt.Sprintf(`{"ref": "refs/heads/master", "repository": {"pushed_at": %d}}`, pushedAt),
+				})
+			}
+
+			resp, err := push("d1")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 200)
+
+			current = current.Add(time.Hour)
+			resp, err = push("d2")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 403)
+			So(resp.Body, ShouldContainSubstring, "payload timestamp '2015-05-05T23:44:00Z' is older than 10m0s")
+		})
+
+		Convey("It doesn't check the age of events without a timestamp of their action", func() {
+			EventHandler(CreateEvent, func(e interface{}) (*Response, error) {
+				return localSuccessResp("ok")
+			})
+			current = current.Add(365 * 24 * time.Hour)
+
+			resp, err := DefaultHandler(&events.APIGatewayProxyRequest{
+				Headers: map[string]string{
+					"X-GitHub-Event":    "create",
+					"X-GitHub-Delivery": "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+				},
+				Body: CreateEventProxyRequest.Body,
+			})
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 200)
+		})
+
+		Convey("It doesn't check the age of actions disabled in TimestampPaths", func() {
+			ReplayProtection.TimestampPaths = map[string][]string{
+				"pull_request":        {"pull_request.updated_at"},
+				"pull_request.opened": nil,
+			}
+			current = current.Add(time.Hour)
+
+			resp, err := DefaultHandler(r)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 200)
+		})
+
+		Convey("It accepts redeliveries of failed deliveries", func() {
+			ResetHandlers()
+			var fnErr error = errors.New("failed")
+			EventHandler(PullRequestEvent, func(e interface{}) (*Response, error) {
+				if fnErr != nil {
+					return nil, fnErr
+				}
+				return localSuccessResp("ok")
+			})
+
+			resp, _ := DefaultHandler(r)
+			So(resp.StatusCode, ShouldEqual, 500)
+
+			fnErr = nil
+			resp, err := DefaultHandler(r)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 200)
+			So(resp.Body, ShouldEqual, "ok")
+		})
+
+		Convey("It rejects invalid payloads as bad requests", func() {
+			resp, err := DefaultHandler(&events.APIGatewayProxyRequest{
+				Headers: r.Headers,
+				Body:    "{",
+			})
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 400)
+		})
+
+		Convey("It rejects deliveries without id", func() {
+			resp, err := DefaultHandler(PullRequestProxyRequest)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 403)
+		})
+	})
+}
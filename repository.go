@@ -0,0 +1,152 @@
+package ghhook
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Visibility is a property of the repository of an event, used with
+// WithVisibility and ExceptVisibility.
+type Visibility string
+
+const (
+	VisibilityPublic   Visibility = "public"
+	VisibilityPrivate  Visibility = "private"
+	VisibilityFork     Visibility = "fork"
+	VisibilityArchived Visibility = "archived"
+)
+
+// InRepos runs the InputFn only for repositories whose full name, ie
+// 'WalkerAndCoBrandsInc/ghhook', matches any of the globs. Globs are case
+// insensitive and '*' doesn't match '/'. It panics if any of the globs is
+// invalid.
+func InRepos(patterns ...string) HandlerOption {
+	globs := mustCompileGlobs(patterns, true)
+
+	return func(c *handlerConfig) {
+		c.add(mapCondition(func(m map[string]interface{}) string {
+			name, ok := lookupPath(m, "repository.full_name").(string)
+			if !ok {
+				return "event has no repository"
+			}
+
+			if !globs.Match(name) {
+				return fmt.Sprintf("repository '%s' doesn't match '%s'", name, globs)
+			}
+
+			return ""
+		}))
+	}
+}
+
+// ExceptRepos doesn't run the InputFn for repositories whose full name matches
+// any of the globs; see InRepos. Events without a repository are not dropped.
+func ExceptRepos(patterns ...string) HandlerOption {
+	globs := mustCompileGlobs(patterns, true)
+
+	return func(c *handlerConfig) {
+		c.add(mapCondition(func(m map[string]interface{}) string {
+			name, ok := lookupPath(m, "repository.full_name").(string)
+			if ok && globs.Match(name) {
+				return fmt.Sprintf("repository '%s' is excluded by '%s'", name, globs)
+			}
+
+			return ""
+		}))
+	}
+}
+
+// InOwners runs the InputFn only for events of the given users or
+// organizations. The owner is the organization of the event if it has one,
+// otherwise the owner of the repository. Names are case insensitive.
+func InOwners(owners ...string) HandlerOption {
+	return func(c *handlerConfig) {
+		c.add(mapCondition(func(m map[string]interface{}) string {
+			owner := eventOwner(m)
+			if owner == "" {
+				return "event has no owner"
+			}
+
+			for _, o := range owners {
+				if strings.EqualFold(o, owner) {
+					return ""
+				}
+			}
+
+			return fmt.Sprintf("owner '%s' is not in '%v'", owner, owners)
+		}))
+	}
+}
+
+// WithVisibility runs the InputFn only for repositories that have any of the
+// given visibilities.
+func WithVisibility(visibilities ...Visibility) HandlerOption {
+	return func(c *handlerConfig) {
+		c.add(mapCondition(func(m map[string]interface{}) string {
+			repo, ok := m["repository"].(map[string]interface{})
+			if !ok {
+				return "event has no repository"
+			}
+
+			for _, v := range visibilities {
+				if hasVisibility(repo, v) {
+					return ""
+				}
+			}
+
+			return fmt.Sprintf("repository '%v' is not '%v'", repo["full_name"], visibilities)
+		}))
+	}
+}
+
+// ExceptVisibility doesn't run the InputFn for repositories that have any of
+// the given visibilities, ie ExceptVisibility(VisibilityFork,
+// VisibilityArchived).
+func ExceptVisibility(visibilities ...Visibility) HandlerOption {
+	return func(c *handlerConfig) {
+		c.add(mapCondition(func(m map[string]interface{}) string {
+			repo, ok := m["repository"].(map[string]interface{})
+			if !ok {
+				return ""
+			}
+
+			for _, v := range visibilities {
+				if hasVisibility(repo, v) {
+					return fmt.Sprintf("repository '%v' is '%s'", repo["full_name"], v)
+				}
+			}
+
+			return ""
+		}))
+	}
+}
+
+func hasVisibility(repo map[string]interface{}, v Visibility) bool {
+	private, _ := repo["private"].(bool)
+
+	switch v {
+	case VisibilityPublic:
+		return !private
+	case VisibilityPrivate:
+		return private
+	case VisibilityFork:
+		fork, _ := repo["fork"].(bool)
+		return fork
+	case VisibilityArchived:
+		archived, _ := repo["archived"].(bool)
+		return archived
+	}
+
+	return false
+}
+
+// eventOwner returns the login of the organization of the event, or of the
+// owner of its repository.
+func eventOwner(m map[string]interface{}) string {
+	if login, ok := lookupPath(m, "organization.login").(string); ok {
+		return login
+	}
+
+	login, _ := lookupPath(m, "repository.owner.login").(string)
+	return login
+}
@@ -0,0 +1,124 @@
+package ghhook
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SenderFilter drops events sent by bots, given logins or a Github App, ie to
+// keep actions taken by our own automation from triggering handlers again.
+type SenderFilter struct {
+	// Bots drops events whose sender is of type 'Bot'.
+	Bots bool
+
+	// Logins drops events of the given senders, compared case insensitively.
+	Logins []string
+
+	// AppSlug and AppID drop events performed by the Github App, ie its bot
+	// user '<slug>[bot]'.
+	AppSlug string
+	AppID   int64
+}
+
+var (
+	// IgnoredSenders drops events of ignored senders before the InputFn
+	// registered with EventHandler and its variants run, except the ones
+	// registered with IncludeBots. InputFn appended to Handlers directly are not
+	// checked. It's disabled when nil.
+	IgnoredSenders *SenderFilter
+)
+
+// Check returns the reason the event is dropped, or an empty string if its
+// sender is not ignored.
+func (f *SenderFilter) Check(m map[string]interface{}) string {
+	login, _ := lookupPath(m, "sender.login").(string)
+	userType, _ := lookupPath(m, "sender.type").(string)
+
+	if f.Bots && isBot(userType, login) {
+		return fmt.Sprintf("sender '%s' is a bot", login)
+	}
+
+	for _, l := range f.Logins {
+		if strings.EqualFold(l, login) {
+			return fmt.Sprintf("sender '%s' is ignored", login)
+		}
+	}
+
+	if f.AppSlug != "" {
+		slug, _ := lookupPath(m, "performed_via_github_app.slug").(string)
+		if strings.EqualFold(login, f.AppSlug+"[bot]") || slug == f.AppSlug {
+			return fmt.Sprintf("sender '%s' is the app '%s'", login, f.AppSlug)
+		}
+	}
+
+	if f.AppID != 0 {
+		if id, ok := lookupPath(m, "performed_via_github_app.id").(float64); ok && int64(id) == f.AppID {
+			return fmt.Sprintf("event was performed by the app '%d'", f.AppID)
+		}
+	}
+
+	return ""
+}
+
+// IgnoreBots doesn't run the InputFn for events whose sender is a bot.
+func IgnoreBots() HandlerOption {
+	return senderOption(&SenderFilter{Bots: true})
+}
+
+// IgnoreSenders doesn't run the InputFn for events of the given logins.
+func IgnoreSenders(logins ...string) HandlerOption {
+	return senderOption(&SenderFilter{Logins: logins})
+}
+
+// IgnoreApp doesn't run the InputFn for events performed by the Github App
+// with the given slug.
+func IgnoreApp(slug string) HandlerOption {
+	return senderOption(&SenderFilter{AppSlug: slug})
+}
+
+// IncludeBots runs the InputFn even for events of senders ignored by
+// IgnoredSenders.
+func IncludeBots() HandlerOption {
+	return func(c *handlerConfig) {
+		c.includeBots = true
+	}
+}
+
+func senderOption(f *SenderFilter) HandlerOption {
+	return func(c *handlerConfig) {
+		c.add(mapCondition(f.Check))
+	}
+}
+
+// checkSenders wraps fn so it doesn't run for senders ignored by
+// IgnoredSenders, unless includeBots is set. The check is part of the
+// registered InputFn, so it stays with it when Handlers is modified.
+func checkSenders(fn InputFn, includeBots bool) InputFn {
+	if includeBots {
+		return fn
+	}
+
+	return func(i interface{}) (*Response, error) {
+		reason, err := traceFilter(i, "ignored_senders", func() (string, error) {
+			if IgnoredSenders == nil {
+				return "", nil
+			}
+
+			m, err := eventMap(i)
+			if err != nil {
+				return "", err
+			}
+
+			return IgnoredSenders.Check(m), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if reason != "" {
+			return dropEvent(i, fmt.Sprintf("Dropping event from ignored sender: %s", reason))
+		}
+
+		return fn(i)
+	}
+}
@@ -0,0 +1,114 @@
+package ghhook
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSenderFilters(t *testing.T) {
+	Convey("Sender filters", t, func() {
+		Reset(func() {
+			ResetHandlers()
+			IgnoredSenders = nil
+		})
+
+		var calls []string
+		handler := func(name string) InputFn {
+			return func(e interface{}) (*Response, error) {
+				calls = append(calls, name)
+				return localSuccessResp(name)
+			}
+		}
+
+		bot := &events.APIGatewayProxyRequest{
+			Headers: map[string]string{"X-GitHub-Event": "issues"},
+			Body: `{
+				"action": "labeled",
+				"sender": {"login": "our-app[bot]", "type": "Bot"},
+				"performed_via_github_app": {"id": 42, "slug": "our-app"}
+			}`,
+		}
+
+		dispatch := func(r *events.APIGatewayProxyRequest) string {
+			resp, err := DefaultHandler(r)
+			So(err, ShouldBeNil)
+			return resp.Body
+		}
+
+		Convey("SenderFilter", func() {
+			m, err := (&payload{body: []byte(bot.Body)}).Map()
+			So(err, ShouldBeNil)
+
+			So((&SenderFilter{}).Check(m), ShouldEqual, "")
+			So((&SenderFilter{Bots: true}).Check(m), ShouldEqual, "sender 'our-app[bot]' is a bot")
+			So((&SenderFilter{Logins: []string{"Our-App[bot]"}}).Check(m), ShouldEqual, "sender 'our-app[bot]' is ignored")
+			So((&SenderFilter{AppSlug: "our-app"}).Check(m), ShouldEqual, "sender 'our-app[bot]' is the app 'our-app'")
+			So((&SenderFilter{AppID: 42}).Check(m), ShouldEqual, "event was performed by the app '42'")
+			So((&SenderFilter{AppSlug: "other-app", AppID: 1}).Check(m), ShouldEqual, "")
+		})
+
+		Convey("IgnoredSenders drops events for every InputFn", func() {
+			IgnoredSenders = &SenderFilter{Bots: true}
+			EventHandler(IssuesEvent, handler("plain"))
+			EventHandlerWithOptions(IssuesEvent, handler("options"))
+
+			So(dispatch(bot), ShouldEqual, "Dropping event from ignored sender: sender 'our-app[bot]' is a bot")
+			So(calls, ShouldBeEmpty)
+		})
+
+		Convey("IncludeBots opts in to events of ignored senders", func() {
+			IgnoredSenders = &SenderFilter{Bots: true}
+			EventHandler(IssuesEvent, handler("plain"))
+			EventHandlerWithOptions(IssuesEvent, handler("bots"), IncludeBots())
+
+			So(dispatch(bot), ShouldEqual, "bots")
+			So(calls, ShouldResemble, []string{"bots"})
+		})
+
+		Convey("IncludeBots follows the InputFn when Handlers is modified", func() {
+			IgnoredSenders = &SenderFilter{Bots: true}
+			EventHandler(IssuesEvent, handler("plain"))
+			EventHandlerWithOptions(IssuesEvent, handler("bots"), IncludeBots())
+
+			fns := Handlers[IssuesEvent]
+			Handlers[IssuesEvent] = []InputFn{fns[1], fns[0]}
+
+			So(dispatch(bot), ShouldEqual, "Dropping event from ignored sender: sender 'our-app[bot]' is a bot")
+			So(calls, ShouldResemble, []string{"bots"})
+		})
+
+		Convey("IncludeBots follows the InputFn when it's wrapped again", func() {
+			IgnoredSenders = &SenderFilter{Bots: true}
+			EventHandlerWithOptions(IssuesEvent, handler("bots"), IncludeBots())
+
+			inner := Handlers[IssuesEvent][0]
+			Handlers[IssuesEvent][0] = func(e interface{}) (*Response, error) {
+				calls = append(calls, "middleware")
+				return inner(e)
+			}
+
+			So(dispatch(bot), ShouldEqual, "bots")
+			So(calls, ShouldResemble, []string{"middleware", "bots"})
+		})
+
+		Convey("IgnoredSenders doesn't drop other senders", func() {
+			IgnoredSenders = &SenderFilter{Bots: true}
+			EventHandler(PullRequestEvent, handler("plain"))
+
+			So(dispatch(PullRequestProxyRequest), ShouldEqual, "plain")
+		})
+
+		Convey("Per handler options", func() {
+			EventHandlerWithOptions(IssuesEvent, handler("bots"), IgnoreBots())
+			So(dispatch(bot), ShouldEqual, "Dropping unmatched event for option: sender 'our-app[bot]' is a bot")
+
+			EventHandlerWithOptions(IssuesEvent, handler("app"), IgnoreApp("our-app"))
+			So(dispatch(bot), ShouldEqual, "Dropping unmatched event for option: sender 'our-app[bot]' is the app 'our-app'")
+
+			EventHandlerWithOptions(IssuesEvent, handler("logins"), IgnoreSenders("someone-else"))
+			So(dispatch(bot), ShouldEqual, "logins")
+		})
+	})
+}
@@ -0,0 +1,111 @@
+package ghhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// SignatureStatus is the result of verifying the signature of a delivery.
+type SignatureStatus string
+
+const (
+	// SignatureUnchecked is the status of deliveries when WebhookSecret is not
+	// set.
+	SignatureUnchecked SignatureStatus = "unchecked"
+	SignatureValid     SignatureStatus = "valid"
+	SignatureInvalid   SignatureStatus = "invalid"
+	SignatureMissing   SignatureStatus = "missing"
+)
+
+var (
+	// WebhookSecret is the secret configured on the Github webhook. When set,
+	// DefaultHandler rejects deliveries whose signature doesn't match the body.
+	WebhookSecret string
+
+	// ErrMissingSignature is returned when WebhookSecret is set and the
+	// delivery has no signature header.
+	ErrMissingSignature error = &ErrUnauthorized{Reason: "no 'X-Hub-Signature-256' or 'X-Hub-Signature' header"}
+
+	// ErrInvalidSignature is returned when the signature of the delivery
+	// doesn't match its body.
+	ErrInvalidSignature error = &ErrUnauthorized{Reason: "signature doesn't match the body"}
+)
+
+// VerifySignature checks the signature of the delivery with the secret. The
+// sha256 signature of 'X-Hub-Signature-256' is used when present, otherwise
+// the sha1 signature of 'X-Hub-Signature'.
+func VerifySignature(secret string, headers map[string]string, body string) error {
+	if signature, ok := headerValue(headers, "X-Hub-Signature-256"); ok {
+		return checkSignature(signature, SignSHA256(secret, body))
+	}
+
+	if signature, ok := headerValue(headers, "X-Hub-Signature"); ok {
+		return checkSignature(signature, SignSHA1(secret, body))
+	}
+
+	return ErrMissingSignature
+}
+
+// verifyDelivery verifies the signature of the request when WebhookSecret is
+// set, and records its status.
+func verifyDelivery(rec *DeliveryRecord, r *events.APIGatewayProxyRequest) error {
+	if WebhookSecret == "" {
+		return nil
+	}
+
+	_, span := startSpan(rec.ctx, "ghhook.signature")
+	defer span.End()
+
+	err := VerifySignature(WebhookSecret, r.Headers, r.Body)
+	rec.Signature = signatureStatus(err)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return err
+}
+
+// SignSHA256 returns the 'X-Hub-Signature-256' header of the body, ie
+// 'sha256=<hex hmac>'.
+func SignSHA256(secret, body string) string {
+	return "sha256=" + signature(sha256.New, secret, body)
+}
+
+// SignSHA1 returns the 'X-Hub-Signature' header of the body, ie
+// 'sha1=<hex hmac>'.
+func SignSHA1(secret, body string) string {
+	return "sha1=" + signature(sha1.New, secret, body)
+}
+
+func signature(h func() hash.Hash, secret, body string) string {
+	mac := hmac.New(h, []byte(secret))
+	mac.Write([]byte(body))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signatureStatus returns the status of the error of VerifySignature.
+func signatureStatus(err error) SignatureStatus {
+	switch err {
+	case nil:
+		return SignatureValid
+	case ErrMissingSignature:
+		return SignatureMissing
+	default:
+		return SignatureInvalid
+	}
+}
+
+func checkSignature(signature, expected string) error {
+	if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package ghhook
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSignature(t *testing.T) {
+	Convey("VerifySignature", t, func() {
+		body := `{"zen":"Keep it logically awesome."}`
+
+		Convey("It signs bodies like Github", func() {
+			So(SignSHA256("It's a Secret to Everybody", "Hello, World!"), ShouldEqual, "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17")
+			So(SignSHA1("secret", body), ShouldStartWith, "sha1=")
+		})
+
+		Convey("It prefers the sha256 signature", func() {
+			headers := map[string]string{
+				"X-Hub-Signature-256": SignSHA256("secret", body),
+				"X-Hub-Signature":     "sha1=invalid",
+			}
+
+			So(VerifySignature("secret", headers, body), ShouldBeNil)
+		})
+
+		Convey("It falls back to the sha1 signature", func() {
+			headers := map[string]string{"x-hub-signature": SignSHA1("secret", body)}
+
+			So(VerifySignature("secret", headers, body), ShouldBeNil)
+			So(VerifySignature("other", headers, body), ShouldEqual, ErrInvalidSignature)
+		})
+
+		Convey("It requires a signature", func() {
+			So(VerifySignature("secret", map[string]string{}, body), ShouldEqual, ErrMissingSignature)
+		})
+	})
+
+	Convey("DefaultHandler with WebhookSecret", t, func() {
+		WebhookSecret = "secret"
+		Reset(func() {
+			WebhookSecret = ""
+			ResetHandlers()
+		})
+		EventHandler(CreateEvent, func(e interface{}) (*Response, error) { return localSuccessResp("ok") })
+
+		Convey("It rejects unsigned deliveries", func() {
+			resp, _ := DefaultHandler(CreateEventProxyRequest)
+
+			So(resp.StatusCode, ShouldEqual, 401)
+		})
+
+		Convey("It accepts signed deliveries", func() {
+			headers := map[string]string{"X-Hub-Signature-256": SignSHA256("secret", CreateEventProxyRequest.Body)}
+			for k, v := range CreateEventProxyRequest.Headers {
+				headers[k] = v
+			}
+
+			resp, err := DefaultHandler(&events.APIGatewayProxyRequest{Headers: headers, Body: CreateEventProxyRequest.Body})
+
+			So(err, ShouldBeNil)
+			So(resp.Body, ShouldEqual, "ok")
+		})
+	})
+}
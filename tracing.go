@@ -0,0 +1,198 @@
+package ghhook
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Span is a unit of work traced by a Tracer. Its methods follow the
+// OpenTelemetry span, so a Tracer can be a thin adapter over an OpenTelemetry
+// tracer.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts spans. The returned context carries the new span, and spans
+// started from it are its children.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// DeliveryTracer is used by DefaultHandler to trace deliveries. Every delivery
+// has a 'ghhook.delivery' span, tagged with its delivery id, event, action and
+// repository, with the following children:
+//
+//   - 'ghhook.signature' when WebhookSecret is set.
+//   - 'ghhook.parse' for parsing the body.
+//   - 'ghhook.handler' for each InputFn, with a 'ghhook.filter' child for each
+//     of its filters.
+//
+// InputFn get the context of their span with ContextOf, to start their own
+// spans.
+var DeliveryTracer Tracer = NoopTracer{}
+
+// ContextOf returns the context of the InputFn running for the event
+// dispatched by DefaultHandler, or context.Background() for other events.
+func ContextOf(e interface{}) context.Context {
+	if p, ok := payloads.Load(e); ok && p.(*payload).ctx != nil {
+		return p.(*payload).ctx
+	}
+
+	return context.Background()
+}
+
+// NoopTracer is a Tracer that doesn't record anything. It's the default
+// DeliveryTracer.
+type NoopTracer struct{}
+
+func (NoopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) RecordError(err error)                      {}
+func (noopSpan) End()                                       {}
+
+// tracing returns true if DeliveryTracer records spans.
+func tracing() bool {
+	_, noop := DeliveryTracer.(NoopTracer)
+	return DeliveryTracer != nil && !noop
+}
+
+// startSpan starts a span with DeliveryTracer, or a no-op span if it's nil.
+func startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if DeliveryTracer == nil {
+		return ctx, noopSpan{}
+	}
+
+	return DeliveryTracer.Start(ctx, name)
+}
+
+// traceFilter runs the filter of the given kind in a 'ghhook.filter' span,
+// child of the span of the running InputFn.
+func traceFilter(i interface{}, kind string, filter func() (string, error)) (string, error) {
+	_, span := startSpan(ContextOf(i), "ghhook.filter")
+	defer span.End()
+
+	span.SetAttribute("ghhook.filter.kind", kind)
+
+	reason, err := filter()
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.SetAttribute("ghhook.filter.dropped", reason != "")
+	if reason != "" {
+		span.SetAttribute("ghhook.filter.reason", reason)
+	}
+
+	return reason, err
+}
+
+// RecordingTracer is a Tracer that keeps spans in memory, ie for tests.
+type RecordingTracer struct {
+	mu     sync.Mutex
+	spans  []*RecordedSpan
+	nextID int
+}
+
+// RecordedSpan is a span recorded by RecordingTracer.
+type RecordedSpan struct {
+	Name string
+
+	// ID is the position of the span in Spans, starting at 1. ParentID is 0
+	// for root spans.
+	ID       int
+	ParentID int
+
+	Attributes map[string]interface{}
+	Errors     []error
+
+	StartTime time.Time
+	EndTime   time.Time
+
+	tracer *RecordingTracer
+}
+
+type recordedSpanKey struct{}
+
+// NewRecordingTracer returns an empty RecordingTracer.
+func NewRecordingTracer() *RecordingTracer {
+	return &RecordingTracer{}
+}
+
+func (t *RecordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.nextID++
+	span := &RecordedSpan{
+		Name:       name,
+		ID:         t.nextID,
+		Attributes: map[string]interface{}{},
+		StartTime:  now(),
+		tracer:     t,
+	}
+
+	if parent, ok := ctx.Value(recordedSpanKey{}).(*RecordedSpan); ok {
+		span.ParentID = parent.ID
+	}
+
+	t.spans = append(t.spans, span)
+
+	return context.WithValue(ctx, recordedSpanKey{}, span), span
+}
+
+// Spans returns the recorded spans in the order they were started.
+func (t *RecordingTracer) Spans() []*RecordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]*RecordedSpan(nil), t.spans...)
+}
+
+// Find returns the recorded spans with the given name.
+func (t *RecordingTracer) Find(name string) []*RecordedSpan {
+	var spans []*RecordedSpan
+	for _, span := range t.Spans() {
+		if span.Name == name {
+			spans = append(spans, span)
+		}
+	}
+
+	return spans
+}
+
+// Reset removes the recorded spans.
+func (t *RecordingTracer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.spans = nil
+	t.nextID = 0
+}
+
+func (s *RecordedSpan) SetAttribute(key string, value interface{}) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+
+	s.Attributes[key] = value
+}
+
+func (s *RecordedSpan) RecordError(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+
+	s.Errors = append(s.Errors, err)
+}
+
+func (s *RecordedSpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+
+	s.EndTime = now()
+}
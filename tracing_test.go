@@ -0,0 +1,86 @@
+package ghhook
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTracing(t *testing.T) {
+	Convey("DeliveryTracer", t, func() {
+		tracer := NewRecordingTracer()
+		DeliveryTracer = tracer
+		Reset(func() {
+			DeliveryTracer = NoopTracer{}
+			ResetHandlers()
+		})
+
+		Convey("It traces the delivery, parsing, handlers and filters", func() {
+			var handlerSpan *RecordedSpan
+			EventHandlerActionFilter(PullRequestEvent, map[string][]string{"action": {"closed"}}, func(e interface{}) (*Response, error) {
+				return localSuccessResp("ok")
+			})
+			EventHandlerWithOptions(PullRequestEvent, func(e interface{}) (*Response, error) {
+				_, span := DeliveryTracer.Start(ContextOf(e), "custom")
+				handlerSpan = span.(*RecordedSpan)
+				span.End()
+				return nil, errors.New("failed")
+			}, InRepos("baxterthehacker/*"))
+
+			DefaultHandler(PullRequestProxyRequest)
+
+			delivery := tracer.Find("ghhook.delivery")
+			So(len(delivery), ShouldEqual, 1)
+			So(delivery[0].ParentID, ShouldEqual, 0)
+			So(delivery[0].Attributes, ShouldResemble, map[string]interface{}{
+				"github.delivery_id": "",
+				"github.event":       "pull_request",
+				"github.action":      "opened",
+				"github.repository":  "baxterthehacker/public-repo",
+				"ghhook.outcome":     "failed",
+				"http.status_code":   500,
+			})
+			So(delivery[0].Errors, ShouldNotBeEmpty)
+			So(delivery[0].EndTime.IsZero(), ShouldBeFalse)
+
+			parse := tracer.Find("ghhook.parse")
+			So(len(parse), ShouldEqual, 1)
+			So(parse[0].ParentID, ShouldEqual, delivery[0].ID)
+
+			handlers := tracer.Find("ghhook.handler")
+			So(len(handlers), ShouldEqual, 2)
+			So(handlers[0].ParentID, ShouldEqual, delivery[0].ID)
+			So(handlers[0].Attributes["ghhook.handler.outcome"], ShouldEqual, "dropped")
+			So(handlers[1].Attributes["ghhook.handler.outcome"], ShouldEqual, "failed")
+			So(handlers[1].Errors, ShouldResemble, []error{errors.New("failed")})
+
+			filters := tracer.Find("ghhook.filter")
+			So(len(filters), ShouldEqual, 4)
+			So(filters[0].Attributes["ghhook.filter.kind"], ShouldEqual, "ignored_senders")
+			So(filters[1].Attributes["ghhook.filter.kind"], ShouldEqual, "action_filter")
+			So(filters[1].Attributes["ghhook.filter.dropped"], ShouldBeTrue)
+			So(filters[1].ParentID, ShouldEqual, handlers[0].ID)
+			So(filters[3].Attributes["ghhook.filter.kind"], ShouldEqual, "option")
+			So(filters[3].Attributes["ghhook.filter.dropped"], ShouldBeFalse)
+			So(filters[3].ParentID, ShouldEqual, handlers[1].ID)
+
+			So(handlerSpan.ParentID, ShouldEqual, handlers[1].ID)
+		})
+
+		Convey("It traces signature verification", func() {
+			WebhookSecret = "secret"
+			Reset(func() { WebhookSecret = "" })
+
+			DefaultHandler(PullRequestProxyRequest)
+
+			signature := tracer.Find("ghhook.signature")
+			So(len(signature), ShouldEqual, 1)
+			So(signature[0].Errors, ShouldResemble, []error{ErrMissingSignature})
+		})
+	})
+
+	Convey("ContextOf returns a background context outside of DefaultHandler", t, func() {
+		So(ContextOf(struct{}{}), ShouldNotBeNil)
+	})
+}